@@ -2,10 +2,10 @@ package bot
 
 import (
 	"errors"
-	"fmt"
 	"log/slog"
 	"os"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +13,7 @@ import (
 	gl "github.com/birabittoh/disgord/src/globals"
 	"github.com/birabittoh/disgord/src/music"
 	"github.com/birabittoh/disgord/src/shoot"
+	"github.com/birabittoh/disgord/src/store"
 	"github.com/bwmarrin/discordgo"
 	"github.com/lmittmann/tint"
 )
@@ -25,18 +26,29 @@ type BotService struct {
 	logger          *slog.Logger
 	interactionsMap map[string]gl.BotInteraction
 	handlersMap     map[string]gl.BotCommand
+	contextMap      map[string]gl.ContextCommand
 	aliasMap        map[string]string
 	commandNames    []string
+	custom          *store.Collection[GuildCustom]
+	cooldowns       *cooldowns
+	metrics         *commandMetrics
+	middlewares     []middleware
 	watchdogDone    chan struct{}
 	ready           chan struct{}
 	readyOnce       sync.Once
 }
 
 func NewBotService(cfg *config.Config) (bs *BotService, err error) {
+	us, err := gl.NewUtilsService(cfg)
+	if err != nil {
+		return nil, errors.New("could not initialize utils service: " + err.Error())
+	}
+
 	bs = &BotService{
-		US:       gl.NewUtilsService(cfg),
-		aliasMap: make(map[string]string),
-		ready:    make(chan struct{}),
+		US:        us,
+		aliasMap:  make(map[string]string),
+		cooldowns: newCooldowns(),
+		ready:     make(chan struct{}),
 	}
 	bs.logger = slog.New(tint.NewHandler(os.Stdout, &tint.Options{
 		Level:      bs.US.Config.LogLevel,
@@ -61,10 +73,18 @@ func NewBotService(cfg *config.Config) (bs *BotService, err error) {
 		}
 	}
 
+	bs.custom, err = store.NewCollection[GuildCustom](bs.US.Store, "custom")
+	if err != nil {
+		return nil, errors.New("could not load custom commands: " + err.Error())
+	}
+
+	bs.initMiddlewares()
 	bs.initHandlers()
 	bs.US.Session.AddHandler(bs.messageHandler)
 	bs.US.Session.AddHandler(bs.readyHandler)
 	bs.US.Session.AddHandler(bs.slashHandler)
+	bs.US.Session.AddHandler(bs.guildCreateHandler)
+	bs.US.Session.AddHandler(bs.guildDeleteHandler)
 	if bs.MS != nil {
 		bs.US.Session.AddHandler(bs.MS.HandleBotVSU)
 	}
@@ -149,24 +169,41 @@ func (bs *BotService) messageHandler(s *discordgo.Session, m *discordgo.MessageC
 
 	bs.logger.Debug("Got a message", "content", m.Content)
 
-	command, response, ok, err := bs.handleCommand(m)
+	ctx := bs.US.MessageContext(m)
+	command, response, ok, err := bs.handleCommand(ctx, m.Content)
 	if err != nil {
 		bs.logger.Error("could not handle command", "error", err)
 		return
 	}
 	if !ok {
+		bs.autoRespond(m)
 		return
 	}
 	if response != nil {
-		msg, err := bs.US.Session.ChannelMessageSendComplex(m.ChannelID, response)
+		msg, err := bs.US.Session.ChannelMessageSendComplex(m.ChannelID, bs.resultToMessage(response, ctx))
 		if err != nil {
 			bs.logger.Error("could not send message", "error", err)
 		} else if msg != nil && command == "search" && bs.MS != nil {
 			bs.MS.SetSearchMessageID(m.ChannelID, m.Author.ID, msg.ID)
 		}
+		if err == nil && response.Sent != nil {
+			response.Sent(msg)
+		}
 	}
 }
 
+// resultToMessage renders a CommandResult for a prefix command. Messages cannot
+// be ephemeral, so failures are sent as replies to the invoking message instead.
+func (bs *BotService) resultToMessage(r *gl.CommandResult, ctx *gl.CommandContext) *discordgo.MessageSend {
+	msg := r.Message
+	ctx.LocalizeResult(r)
+	if r.Kind != gl.ResultOK && ctx.Message != nil && ctx.Message.ID != "" {
+		msg.Reference = ctx.Message.Reference()
+		msg.AllowedMentions = &discordgo.MessageAllowedMentions{}
+	}
+	return msg
+}
+
 func (bs *BotService) readyHandler(s *discordgo.Session, r *discordgo.Ready) {
 	s.UpdateStatusComplex(discordgo.UpdateStatusData{
 		Status: "online",
@@ -183,52 +220,226 @@ func (bs *BotService) readyHandler(s *discordgo.Session, r *discordgo.Ready) {
 }
 
 func (bs *BotService) initHandlers() {
-	defaultSearchOptions := []gl.SlashOption{
-		{
-			Type:        discordgo.ApplicationCommandOptionString,
-			Name:        gl.DefaultSearchOptionName,
-			Description: gl.DefaultSearchOptionDescription,
-			Required:    true,
-		},
+	minVolume, minIndex := 0.0, 1.0
+	// commands that query Deezer share these limits, so spamming them does not get the account throttled
+	searchCooldown := gl.Cooldown{Uses: 3, Period: 10 * time.Second, Group: "search"}
+	fetchCooldown := gl.Cooldown{Uses: 2, Period: 20 * time.Second, Group: "fetch"}
+	helpOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "command", Description: "command to describe, all of them if empty", Autocomplete: true},
+	}
+	echoOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "text", Description: "text to echo", Required: true},
+	}
+	trackSearchOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "query", Description: "song to look for", Required: true, Autocomplete: true},
+	}
+	playOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "query", Description: "song to look for", Required: true, Autocomplete: true},
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "position", Description: "position in the queue, the end if empty", MinValue: &minIndex, Flag: true},
+	}
+	seekOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "position", Description: "position to seek to, e.g. 1m30s", Required: true, Duration: true},
+	}
+	volumeOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "level", Description: "volume in percent", MinValue: &minVolume, MaxValue: float64(gl.AudioMaxVolume)},
+	}
+	removeOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "index", Description: "position in the queue", Required: true, MinValue: &minIndex},
+	}
+	moveOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "from", Description: "current position in the queue", Required: true, MinValue: &minIndex},
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "to", Description: "new position in the queue", Required: true, MinValue: &minIndex},
+	}
+	queueCommands := map[string]gl.BotCommand{
+		"show":    {Handler: bs.MS.HandleQueue, Help: "shows the current queue", Slow: true},
+		"shuffle": {Handler: bs.MS.HandleQueueShuffle, Help: "shuffles the upcoming songs"},
+		"remove":  {ShortCode: "r", Handler: bs.MS.HandleRemove, Help: "removes a song from the queue", Examples: []string{"2"}, SlashOptions: removeOptions},
+		"move":    {ShortCode: "m", Handler: bs.MS.HandleQueueMove, Help: "moves a song to another position", Examples: []string{"5 1"}, SlashOptions: moveOptions},
+	}
+	lyricsOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "query", Description: "song to look for, the current one if empty", Autocomplete: true},
+		{Type: discordgo.ApplicationCommandOptionBoolean, Name: "live", Description: "follows the synced lyrics of the current song", Flag: true},
+	}
+	catalogChoices := []*discordgo.ApplicationCommandOptionChoice{
+		{Name: music.KindAlbum, Value: music.KindAlbum},
+		{Name: music.KindArtist, Value: music.KindArtist},
+		{Name: music.KindPlaylist, Value: music.KindPlaylist},
+	}
+	searchOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "query", Description: "song to look for, leave empty for more options", Autocomplete: true},
+		{Type: discordgo.ApplicationCommandOptionString, Name: "type", Description: "looks for albums, artists or playlists instead of songs", Choices: catalogChoices, Flag: true},
+	}
+	catalogOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "query", Description: "name to look for", Required: true},
+	}
+	catalogCommands := map[string]gl.BotCommand{
+		"album":    {Handler: bs.MS.HandleSearchAlbum, Help: "searches for an album and its songs", Examples: []string{"discovery"}, SlashOptions: catalogOptions, Slow: true, Cooldown: searchCooldown},
+		"artist":   {Handler: bs.MS.HandleSearchArtist, Help: "searches for an artist and their top songs", SlashOptions: catalogOptions, Slow: true, Cooldown: searchCooldown},
+		"playlist": {Handler: bs.MS.HandleSearchPlaylist, Help: "searches for a public playlist", SlashOptions: catalogOptions, Slow: true, Cooldown: searchCooldown},
+	}
+	playlistNameOption := gl.SlashOption{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "playlist name", Required: true, Autocomplete: true}
+	playlistSaveOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "playlist name"},
+		music.PlaylistScopeOption,
+	}
+	playlistAddOptions := []gl.SlashOption{
+		playlistNameOption,
+		{Type: discordgo.ApplicationCommandOptionString, Name: "query", Description: "song to add", Required: true},
+	}
+	playlistRemoveOptions := []gl.SlashOption{
+		playlistNameOption,
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "index", Description: "position in the playlist", Required: true, MinValue: &minIndex},
+	}
+	formatChoices := []*discordgo.ApplicationCommandOptionChoice{
+		{Name: music.FormatM3U, Value: music.FormatM3U},
+		{Name: music.FormatXSPF, Value: music.FormatXSPF},
+		{Name: music.FormatJSON, Value: music.FormatJSON},
+	}
+	playlistExportOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "format", Description: "file format", Required: true, Choices: formatChoices},
+		{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "playlist to export, the current queue if empty", Autocomplete: true},
+	}
+	playlistImportOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionAttachment, Name: "file", Description: "M3U8, XSPF or JSON playlist", Required: true},
+		{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "playlist name, taken from the file if empty"},
+		music.PlaylistScopeOption,
+	}
+	playlistCommands := map[string]gl.BotCommand{
+		"save":   {ShortCode: "s", Handler: bs.MS.HandlePlaylistSave, Modal: bs.MS.PlaylistForm, Help: "saves the current queue as a playlist", SlashOptions: playlistSaveOptions},
+		"load":   {ShortCode: "l", Handler: bs.MS.HandlePlaylistLoad, Autocomplete: bs.MS.HandlePlaylistAutocomplete, Help: "adds a playlist to the queue", SlashOptions: []gl.SlashOption{playlistNameOption}, Slow: true, Cooldown: fetchCooldown},
+		"add":    {ShortCode: "a", Handler: bs.MS.HandlePlaylistAdd, Autocomplete: bs.MS.HandlePlaylistAutocomplete, Help: "adds a song to a playlist", Examples: []string{"roadtrip bohemian rhapsody"}, SlashOptions: playlistAddOptions, Slow: true, Cooldown: searchCooldown},
+		"remove": {ShortCode: "r", Handler: bs.MS.HandlePlaylistRemove, Autocomplete: bs.MS.HandlePlaylistAutocomplete, Help: "removes a song from a playlist", SlashOptions: playlistRemoveOptions},
+		"list":   {ShortCode: "ls", Handler: bs.MS.HandlePlaylistList, Help: "lists the saved playlists", SlashOptions: []gl.SlashOption{music.PlaylistScopeOption}},
+		"show":   {Handler: bs.MS.HandlePlaylistShow, Autocomplete: bs.MS.HandlePlaylistAutocomplete, Help: "shows the songs in a playlist", SlashOptions: []gl.SlashOption{playlistNameOption}, Slow: true},
+		"export": {ShortCode: "e", Handler: bs.MS.HandlePlaylistExport, Autocomplete: bs.MS.HandlePlaylistAutocomplete, Help: "exports the queue or a playlist as a file", Examples: []string{"m3u8", "json roadtrip"}, SlashOptions: playlistExportOptions, Slow: true, Cooldown: fetchCooldown},
+		"import": {ShortCode: "i", Handler: bs.MS.HandlePlaylistImport, Help: "imports a playlist from an attached file", SlashOptions: playlistImportOptions, Slow: true, Cooldown: fetchCooldown},
+		"delete": {ShortCode: "d", Handler: bs.MS.HandlePlaylistDelete, Autocomplete: bs.MS.HandlePlaylistAutocomplete, Help: "deletes a playlist", SlashOptions: []gl.SlashOption{playlistNameOption}},
+	}
+	periodChoices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(music.StatsPeriods))
+	for _, period := range music.StatsPeriods {
+		periodChoices = append(periodChoices, &discordgo.ApplicationCommandOptionChoice{Name: period, Value: period})
+	}
+	statsOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "period", Description: "time window, a week if empty", Choices: periodChoices},
+	}
+	topCommands := map[string]gl.BotCommand{
+		"tracks":    {ShortCode: "t", Handler: bs.MS.HandleTopTracks, Help: "shows the most played songs", Examples: []string{"month"}, SlashOptions: statsOptions},
+		"artists":   {ShortCode: "a", Handler: bs.MS.HandleTopArtists, Help: "shows the most played artists", SlashOptions: statsOptions},
+		"listeners": {ShortCode: "l", Handler: bs.MS.HandleTopListeners, Help: "shows who requested the most songs", SlashOptions: statsOptions},
+	}
+	shootOptions := []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionUser, Name: "target", Description: "who to aim at"},
+	}
+
+	// modules disabled in the config cannot be turned on per server
+	var moduleChoices []*discordgo.ApplicationCommandOptionChoice
+	for _, module := range []struct {
+		tag      string
+		disabled bool
+	}{
+		{gl.TagMusic, bs.US.Config.DisableMusic},
+		{gl.TagShoot, bs.US.Config.DisableShoot},
+		{gl.TagCustom, false},
+	} {
+		if !module.disabled {
+			moduleChoices = append(moduleChoices, &discordgo.ApplicationCommandOptionChoice{Name: module.tag, Value: module.tag})
+		}
+	}
+	settingsCommands := map[string]gl.BotCommand{
+		"module": {Handler: bs.handleSettingsModule, Help: "enables or disables a module in this server", Examples: []string{"shoot off"}, SlashOptions: []gl.SlashOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "module", Description: "module to toggle", Required: true, Choices: moduleChoices},
+			{Type: discordgo.ApplicationCommandOptionBoolean, Name: "enabled", Description: "whether the module is enabled", Required: true},
+		}},
+		"language": {Handler: bs.handleSettingsLanguage, Help: "changes the language of the bot in this server", Examples: []string{"it"}, SlashOptions: []gl.SlashOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "language", Description: "language to answer in", Required: true, Choices: bs.languageChoices()},
+		}},
+	}
+	customNameOption := gl.SlashOption{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "command name", Required: true}
+	customResponseOption := gl.SlashOption{Type: discordgo.ApplicationCommandOptionString, Name: "response", Description: "reply, may use {user}, {channel}, {args} and {1} to {9}", Required: true}
+	customCommands := map[string]gl.BotCommand{
+		"add":    {ShortCode: "a", Handler: bs.handleCustomAdd, Help: "adds or replaces a custom command", Examples: []string{"welcome Welcome to {channel}, {user}!"}, SlashOptions: []gl.SlashOption{customNameOption, customResponseOption}, Permissions: discordgo.PermissionManageGuild},
+		"remove": {ShortCode: "r", Handler: bs.handleCustomRemove, Autocomplete: bs.handleCustomAutocomplete, Help: "removes a custom command", SlashOptions: []gl.SlashOption{{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "command name", Required: true, Autocomplete: true}}, Permissions: discordgo.PermissionManageGuild},
+		"list":   {ShortCode: "ls", Handler: bs.handleCustomList, Help: "lists the custom commands of this server"},
+	}
+	triggerOption := gl.SlashOption{Type: discordgo.ApplicationCommandOptionString, Name: "trigger", Description: "words to answer to", Required: true}
+	autoResponseCommands := map[string]gl.BotCommand{
+		"add":    {ShortCode: "a", Handler: bs.handleAutoResponseAdd, Help: "answers whenever a message contains some words", Examples: []string{"ping pong!"}, SlashOptions: []gl.SlashOption{triggerOption, customResponseOption}, Permissions: discordgo.PermissionManageGuild},
+		"remove": {ShortCode: "r", Handler: bs.handleAutoResponseRemove, Autocomplete: bs.handleAutoResponseAutocomplete, Help: "removes an auto-response", SlashOptions: []gl.SlashOption{{Type: discordgo.ApplicationCommandOptionString, Name: "trigger", Description: "words to answer to", Required: true, Autocomplete: true}}, Permissions: discordgo.PermissionManageGuild},
+		"list":   {ShortCode: "ls", Handler: bs.handleAutoResponseList, Help: "lists the auto-responses of this server"},
 	}
 
 	bs.handlersMap = map[string]gl.BotCommand{
-		"help":   {ShortCode: "h", Handler: bs.handleHelp, Help: "shows a help message", Tag: "general"},
-		"echo":   {ShortCode: "e", Handler: bs.handleEcho, Help: "echoes a message", SlashOptions: defaultSearchOptions, Tag: "general"},
-		"play":   {ShortCode: "p", Handler: bs.MS.HandlePlay, Help: "plays a song", SlashOptions: defaultSearchOptions, Tag: "music"},
-		"search": {ShortCode: "f", Handler: bs.MS.HandleSearch, Help: "searches for a song", SlashOptions: defaultSearchOptions, Tag: "music"},
-		"lyrics": {ShortCode: "l", Handler: bs.MS.HandleLyrics, Help: "shows the lyrics of the current song", Tag: "music"},
-		"seek":   {ShortCode: "se", Handler: bs.MS.HandleSeek, Help: "seeks to a specific position in the current song", SlashOptions: defaultSearchOptions, Tag: "music"},
-		"skip":   {ShortCode: "s", Handler: bs.MS.HandleSkip, Help: "skips the current song", Tag: "music"},
-		"queue":  {ShortCode: "q", Handler: bs.MS.HandleQueue, Help: "shows the current queue", Tag: "music"},
-		"clear":  {ShortCode: "c", Handler: bs.MS.HandleClear, Help: "clears the current queue", Tag: "music"},
-		"leave":  {Alias: "stop", Handler: bs.MS.HandleLeave, Help: "leaves the voice channel", Tag: "music"},
-		"debug":  {ShortCode: "d", Handler: bs.MS.HandleDebugSound, Help: "plays a debug tone in voice channel", Tag: "music"},
-		"shoot":  {Alias: "bang", Handler: bs.SS.HandleShoot, Help: "shoots a random user in your voice channel", Tag: "shoot"},
+		"help":     {ShortCode: "h", Handler: bs.handleHelp, Autocomplete: bs.handleHelpAutocomplete, Help: "shows a help message", Examples: []string{"play", "playlist add"}, SlashOptions: helpOptions, Tag: gl.TagGeneral},
+		"settings": {Help: "changes the bot settings for this server", Subcommands: settingsCommands, Permissions: discordgo.PermissionManageGuild, Tag: gl.TagGeneral},
+		"custom":   {Help: "manages the custom commands of this server", Subcommands: customCommands, Tag: gl.TagGeneral},
+		"trigger":  {Help: "manages the words the bot answers to in this server", Subcommands: autoResponseCommands, Tag: gl.TagGeneral},
+		"echo":     {ShortCode: "e", Handler: bs.handleEcho, Help: "echoes a message", SlashOptions: echoOptions, Tag: gl.TagGeneral},
+		"play":     {ShortCode: "p", Handler: bs.MS.HandlePlay, Autocomplete: bs.MS.HandleTrackAutocomplete, Help: "plays a song", Examples: []string{"bohemian rhapsody", "one more time --position 1"}, SlashOptions: playOptions, Slow: true, Cooldown: searchCooldown, Tag: gl.TagMusic},
+		"playnext": {ShortCode: "pn", Handler: bs.MS.HandlePlayNext, Autocomplete: bs.MS.HandleTrackAutocomplete, Help: "plays a song after the current one", SlashOptions: trackSearchOptions, Slow: true, Cooldown: searchCooldown, Tag: gl.TagMusic},
+		"search":   {ShortCode: "f", Handler: bs.MS.HandleSearch, Autocomplete: bs.MS.HandleTrackAutocomplete, Modal: bs.MS.SearchForm, Help: "searches for a song, album, artist or playlist", Examples: []string{"one more time", "album discovery"}, SlashOptions: searchOptions, Subcommands: catalogCommands, Slow: true, Cooldown: searchCooldown, Tag: gl.TagMusic},
+		"lyrics":   {ShortCode: "l", Handler: bs.MS.HandleLyrics, Help: "shows the lyrics of a song", SlashOptions: lyricsOptions, Slow: true, Cooldown: searchCooldown, Tag: gl.TagMusic},
+		"seek":     {ShortCode: "se", Handler: bs.MS.HandleSeek, Help: "seeks to a specific position in the current song", Examples: []string{"1m30s", "45s"}, SlashOptions: seekOptions, Tag: gl.TagMusic},
+		"skip":     {ShortCode: "s", Handler: bs.MS.HandleSkip, Help: "skips the current song", Tag: gl.TagMusic},
+		"previous": {Alias: "back", ShortCode: "b", Handler: bs.MS.HandlePrevious, Help: "goes back to the previous song", Tag: gl.TagMusic},
+		"replay":   {ShortCode: "rp", Handler: bs.MS.HandleReplay, Help: "restarts the current song", Tag: gl.TagMusic},
+		"queue":    {ShortCode: "q", Handler: bs.MS.HandleQueue, Help: "shows and edits the current queue", Subcommands: queueCommands, Slow: true, Tag: gl.TagMusic},
+		"volume":   {ShortCode: "v", Handler: bs.MS.HandleVolume, Help: "shows or sets the playback volume", Examples: []string{"50"}, SlashOptions: volumeOptions, Tag: gl.TagMusic},
+		"clear":    {ShortCode: "c", Handler: bs.MS.HandleClear, Help: "clears the current queue", Tag: gl.TagMusic},
+		"leave":    {Alias: "stop", Handler: bs.MS.HandleLeave, Help: "leaves the voice channel", Tag: gl.TagMusic},
+		"debug":    {ShortCode: "d", Handler: bs.MS.HandleDebugSound, Help: "plays a debug tone in voice channel", Slow: true, Tag: gl.TagMusic},
+		"playlist": {ShortCode: "pl", Help: "manages saved playlists", Subcommands: playlistCommands, Tag: gl.TagMusic},
+		"history":  {Handler: bs.MS.HandleHistory, Help: "shows the latest songs played in this server", SlashOptions: statsOptions, Tag: gl.TagMusic},
+		"top":      {Help: "shows the most played songs, artists and listeners", Subcommands: topCommands, Tag: gl.TagMusic},
+		"shoot":    {Alias: "bang", Handler: bs.SS.HandleShoot, Help: "shoots a random user in your voice channel", Examples: []string{"@someone"}, SlashOptions: shootOptions, Tag: gl.TagShoot},
 	}
 
 	bs.interactionsMap = map[string]gl.BotInteraction{
-		"choose_track": {Handler: bs.MS.HandleChooseTrack, Tag: "music"},
+		"choose_track":  {Handler: bs.MS.HandleChooseTrack, Slow: true, Cooldown: searchCooldown, Tag: gl.TagMusic},
+		"now_playing":   {Handler: bs.MS.HandleNowPlayingControl, Tag: gl.TagMusic},
+		"search":        {Handler: bs.MS.HandleSearchForm, Slow: true, Cooldown: searchCooldown, Tag: gl.TagMusic},
+		"playlist_save": {Handler: bs.MS.HandlePlaylistSaveForm, Tag: gl.TagMusic},
+		"lyrics":        {Handler: bs.MS.HandleLyricsPage, Tag: gl.TagMusic},
+		"play_track":    {Handler: bs.MS.HandlePlayTrack, Slow: true, Cooldown: searchCooldown, Tag: gl.TagMusic},
+		"play_tracks":   {Handler: bs.MS.HandlePlayTracks, Slow: true, Cooldown: fetchCooldown, Tag: gl.TagMusic},
+		"browse":        {Handler: bs.MS.HandleBrowse, Slow: true, Cooldown: searchCooldown, Tag: gl.TagMusic},
+		"help":          {Handler: bs.handleHelpCategory, Tag: gl.TagGeneral},
+		"run_command":   {Handler: bs.handleRunCommand, Slow: true, Tag: gl.TagGeneral},
+	}
+
+	bs.contextMap = map[string]gl.ContextCommand{
+		"Shoot this user":                {Type: discordgo.UserApplicationCommand, Handler: bs.SS.HandleShoot, Option: "target", Tag: gl.TagShoot},
+		"Show what they're listening to": {Type: discordgo.UserApplicationCommand, Handler: bs.MS.HandleListening, Option: "user", Slow: true, Tag: gl.TagMusic},
+		"Play this link":                 {Type: discordgo.MessageApplicationCommand, Handler: bs.MS.HandlePlay, Option: "query", Slow: true, Cooldown: searchCooldown, Tag: gl.TagMusic},
+		"Search lyrics for this text":    {Type: discordgo.MessageApplicationCommand, Handler: bs.MS.HandleLyricsSearch, Option: "query", Slow: true, Cooldown: searchCooldown, Tag: gl.TagMusic},
 	}
 
 	for key, cmd := range bs.handlersMap {
-		if cmd.Tag == "shoot" && bs.US.Config.DisableShoot {
+		if cmd.Tag == gl.TagShoot && bs.US.Config.DisableShoot {
 			delete(bs.handlersMap, key)
 		}
-		if cmd.Tag == "music" && bs.US.Config.DisableMusic {
+		if cmd.Tag == gl.TagMusic && bs.US.Config.DisableMusic {
 			delete(bs.handlersMap, key)
 		}
 	}
 
 	for key, interaction := range bs.interactionsMap {
-		if interaction.Tag == "music" && bs.US.Config.DisableMusic {
+		if interaction.Tag == gl.TagMusic && bs.US.Config.DisableMusic {
 			delete(bs.interactionsMap, key)
 		}
-		if interaction.Tag == "shoot" && bs.US.Config.DisableShoot {
+		if interaction.Tag == gl.TagShoot && bs.US.Config.DisableShoot {
 			delete(bs.interactionsMap, key)
 		}
 	}
 
+	for key, cmd := range bs.contextMap {
+		if cmd.Tag == gl.TagMusic && bs.US.Config.DisableMusic {
+			delete(bs.contextMap, key)
+		}
+		if cmd.Tag == gl.TagShoot && bs.US.Config.DisableShoot {
+			delete(bs.contextMap, key)
+		}
+	}
+
 	for command, botCommand := range bs.handlersMap {
 		if botCommand.ShortCode != "" {
 			bs.aliasMap[botCommand.ShortCode] = command
@@ -259,13 +470,31 @@ func (bs *BotService) getCommand(name string) *gl.BotCommand {
 	return &botCommand
 }
 
-func (bs *BotService) handleCommand(m *discordgo.MessageCreate) (command string, response *discordgo.MessageSend, ok bool, err error) {
+// resolveSubcommand walks down the subcommands of bc following the leading
+// words of args. It stops at the first word that is not a subcommand, so a
+// group with a handler of its own acts as the default for unknown words.
+func resolveSubcommand(bc *gl.BotCommand, path, args string) (*gl.BotCommand, string, string) {
+	for bc.HasSubcommands() {
+		word, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+		name, sub := bc.Subcommand(word)
+		if sub == nil {
+			break
+		}
+
+		bc, path, args = sub, path+" "+name, rest
+	}
+	return bc, path, args
+}
+
+// handleCommand runs the prefix command line content on behalf of ctx, which
+// comes from a message or from the button that sent the line.
+func (bs *BotService) handleCommand(ctx *gl.CommandContext, content string) (command string, response *gl.CommandResult, ok bool, err error) {
 	if bs.US.Config.DisablePrefixCommands {
 		return "", nil, false, nil
 	}
 
 	var args string
-	command, args, ok = bs.US.ParseUserMessage(m.Content)
+	command, args, ok = bs.US.ParseUserMessage(content)
 	if !ok {
 		return
 	}
@@ -276,27 +505,120 @@ func (bs *BotService) handleCommand(m *discordgo.MessageCreate) (command string,
 
 	bc := bs.getCommand(command)
 	if bc == nil {
-		response = bs.US.EmbedMessage(fmt.Sprintf(gl.MsgUnknownCommand, bs.US.FormatCommand(command)))
+		c, found := bs.customCommand(ctx.GuildID, command)
+		if !found {
+			response = bs.unknownCommand(command, args, ctx)
+			return
+		}
+
+		ctx.Command = command
+		response = bs.run(&invocation{
+			ctx:      ctx,
+			name:     command,
+			shown:    bs.US.FormatCommand(command),
+			tag:      gl.TagCustom,
+			cooldown: customCooldown,
+			handler: func(ctx *gl.CommandContext) *gl.CommandResult {
+				return bs.runCustomCommand(c, args, ctx)
+			},
+		})
 		return
 	}
 
-	response = bc.Handler(args, m)
+	var path string
+	top := bc
+	bc, path, args = resolveSubcommand(bc, command, args)
+
+	handler := bc.Handler
+	if handler == nil {
+		// groups explain their usage, still behind the module and permission checks
+		subcommands := strings.Join(bc.SubcommandNames(), "|")
+		handler = func(ctx *gl.CommandContext) *gl.CommandResult {
+			return ctx.UserError(gl.MsgUsageSubcommand, bs.US.FormatCommand(path), subcommands)
+		}
+	} else if ctx.Options, err = gl.ParseOptions(bc.SlashOptions, args); err != nil {
+		return command, bs.US.UserError(ctx.TError(err)), ok, nil
+	}
+
+	ctx.Command = path
+	response = bs.run(&invocation{
+		ctx:         ctx,
+		name:        command,
+		shown:       bs.US.FormatCommand(command),
+		tag:         top.Tag,
+		permissions: top.Permissions | bc.Permissions,
+		cooldown:    bc.Cooldown,
+		handler:     handler,
+	})
 	return
 }
 
-func (bs *BotService) handleEcho(args string, m *discordgo.MessageCreate) *discordgo.MessageSend {
-	if len(args) == 0 {
+func (bs *BotService) handleEcho(ctx *gl.CommandContext) *gl.CommandResult {
+	text := ctx.Options.String("text")
+	if len(text) == 0 {
 		return nil
 	}
-	return bs.US.EmbedMessage(args)
+	return bs.US.Reply(text)
 }
 
-func (bs *BotService) handleHelp(args string, m *discordgo.MessageCreate) *discordgo.MessageSend {
-	helpText := gl.MsgHelp
+func (bs *BotService) handleSettingsModule(ctx *gl.CommandContext) *gl.CommandResult {
+	if ctx.GuildID == "" {
+		return ctx.UserError(gl.MsgUseInServer)
+	}
+
+	module, enabled := ctx.Options.String("module"), ctx.Options.Bool("enabled")
+	err := bs.US.Settings.Update(ctx.GuildID, func(gs *gl.GuildSettings) {
+		// the stored slice is shared with readers
+		gs.DisabledModules = slices.DeleteFunc(slices.Clone(gs.DisabledModules), func(t string) bool { return t == module })
+		if !enabled {
+			gs.DisabledModules = append(gs.DisabledModules, module)
+		}
+	})
+	if err != nil {
+		return ctx.InternalError(errors.New("could not save guild settings: " + err.Error()))
+	}
+
+	if bs.guildScoped() {
+		go func() {
+			if err := bs.syncGuildCommands(ctx.GuildID); err != nil {
+				bs.logger.Error("could not register guild slash commands", "guild", ctx.GuildID, "error", err)
+			}
+		}()
+	}
+
+	state := ctx.T(gl.MsgDisabled)
+	if enabled {
+		state = ctx.T(gl.MsgEnabled)
+	}
+	return ctx.Reply(gl.MsgModuleToggledFmt, module, state)
+}
 
-	for _, command := range bs.commandNames {
-		helpText += fmt.Sprintf(gl.MsgUnorderedList, bs.US.FormatHelp(command, bs.handlersMap[command]))
+func (bs *BotService) handleSettingsLanguage(ctx *gl.CommandContext) *gl.CommandResult {
+	if ctx.GuildID == "" {
+		return ctx.UserError(gl.MsgUseInServer)
+	}
+
+	locale := ctx.Options.String("language")
+	err := bs.US.Settings.Update(ctx.GuildID, func(gs *gl.GuildSettings) {
+		gs.Locale = locale
+	})
+	if err != nil {
+		return ctx.InternalError(errors.New("could not save guild settings: " + err.Error()))
 	}
 
-	return bs.US.EmbedMessage(helpText)
+	// answered in the new language
+	return ctx.Reply(gl.MsgLanguageSetFmt, locale)
+}
+
+// languageChoices offers the default language along with the ones that have a catalog.
+func (bs *BotService) languageChoices() []*discordgo.ApplicationCommandOptionChoice {
+	locales := append([]string{bs.US.Config.Locale}, bs.US.Translator.Locales()...)
+	slices.Sort(locales)
+	locales = slices.Compact(locales)
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(locales))
+	for _, locale := range locales[:min(len(locales), gl.DiscordMaxChoices)] {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: locale, Value: locale})
+	}
+	return choices
 }
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"cmp"
+	"sync"
+	"time"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+)
+
+// cooldownSweep is how often uses that no longer count are forgotten, so
+// cooldown periods must be shorter than this.
+const cooldownSweep = 10 * time.Minute
+
+// cooldowns remembers the recent uses of the commands that have a Cooldown.
+type cooldowns struct {
+	mu    sync.Mutex
+	uses  map[string][]time.Time // by command and user or guild, oldest first
+	swept time.Time
+}
+
+func newCooldowns() *cooldowns {
+	return &cooldowns{uses: map[string][]time.Time{}}
+}
+
+// take records a use under key and returns zero, or how long to wait when the
+// limit of cd is already reached; refused uses are not recorded.
+func (c *cooldowns) take(key string, cd gl.Cooldown, now time.Time) time.Duration {
+	if cd.Period <= 0 {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.swept) > cooldownSweep {
+		c.sweep(now)
+	}
+
+	recent := c.uses[key]
+	for len(recent) > 0 && !recent[0].Add(cd.Period).After(now) {
+		recent = recent[1:]
+	}
+	if len(recent) >= max(1, cd.Uses) {
+		c.uses[key] = recent
+		return recent[0].Add(cd.Period).Sub(now)
+	}
+	c.uses[key] = append(recent, now)
+	return 0
+}
+
+// sweep drops the keys whose last use is older than any cooldown period.
+func (c *cooldowns) sweep(now time.Time) {
+	for key, uses := range c.uses {
+		if len(uses) == 0 || now.Sub(uses[len(uses)-1]) > cooldownSweep {
+			delete(c.uses, key)
+		}
+	}
+	c.swept = now
+}
+
+// autocompleteCooldown limits the suggestions of the commands that have a
+// cooldown, which search as the user types. They count apart from the
+// commands, so that typing does not use up their runs.
+var autocompleteCooldown = gl.Cooldown{Uses: 20, Period: 10 * time.Second, Group: "autocomplete"}
+
+// checkCooldowns counts a use of the command by the author, answering with
+// the time left when the command cannot be used yet.
+func (bs *BotService) checkCooldowns(inv *invocation, next func() *gl.CommandResult) *gl.CommandResult {
+	ctx := inv.ctx
+	wait := bs.takeCooldown(inv.name, inv.cooldown, ctx.GuildID, ctx.Author.ID)
+	if wait <= 0 {
+		return next()
+	}
+	return ctx.UserError(gl.MsgCooldownFmt, inv.shown, wait)
+}
+
+// takeCooldown counts a use of the command name, or of its cooldown group, by
+// userID and returns zero, or how long to wait, rounded up to the second.
+func (bs *BotService) takeCooldown(name string, cd gl.Cooldown, guildID, userID string) time.Duration {
+	if bs.US.Config.DisableCooldowns {
+		return 0
+	}
+
+	bucket := cmp.Or(cd.Group, name)
+	key := bucket + ":" + userID
+	if cd.PerGuild && guildID != "" {
+		key = bucket + ":guild:" + guildID
+	}
+
+	wait := bs.cooldowns.take(key, cd, time.Now())
+	if wait <= 0 {
+		return 0
+	}
+	return (wait + time.Second - 1).Truncate(time.Second)
+}
@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+)
+
+func TestCooldownsTake(t *testing.T) {
+	c := newCooldowns()
+	cd := gl.Cooldown{Uses: 2, Period: 10 * time.Second}
+	start := time.Now()
+
+	steps := []struct {
+		key   string
+		after time.Duration
+		want  time.Duration
+	}{
+		{"play:a", 0, 0},
+		{"play:a", time.Second, 0},
+		{"play:a", 2 * time.Second, 8 * time.Second},
+		{"play:b", 2 * time.Second, 0},
+		{"play:a", 10 * time.Second, 0},
+		{"play:a", 11 * time.Second, 0},
+		{"play:a", 12 * time.Second, 8 * time.Second},
+	}
+	for n, step := range steps {
+		if got := c.take(step.key, cd, start.Add(step.after)); got != step.want {
+			t.Errorf("step %d: take(%q) = %v, want %v", n, step.key, got, step.want)
+		}
+	}
+
+	if got := c.take("play:a", gl.Cooldown{}, start); got != 0 {
+		t.Errorf("take without cooldown = %v, want 0", got)
+	}
+}
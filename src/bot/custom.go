@@ -0,0 +1,413 @@
+package bot
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	maxCustomCommands  = 50
+	maxAutoResponses   = 25
+	maxCustomNameRunes = 32
+	customPreviewRunes = 60
+)
+
+var (
+	ErrCustomNotFound     = errors.New("custom command not found")
+	ErrCustomReserved     = errors.New("name is taken by a bot command")
+	ErrCustomName         = errors.New("invalid name")
+	ErrCustomResponse     = errors.New("invalid response")
+	ErrCustomLimit        = errors.New("too many custom commands")
+	ErrAutoResponseNotFnd = errors.New("auto-response not found")
+	ErrAutoResponseLimit  = errors.New("too many auto-responses")
+)
+
+// CustomCommand is a text command written by the admins of a guild.
+type CustomCommand struct {
+	Name     string    `json:"name"`
+	Response string    `json:"response"`
+	Author   string    `json:"author,omitempty"` // empty when made from the web UI
+	Created  time.Time `json:"created"`
+}
+
+// AutoResponse is sent whenever a message mentions Trigger.
+type AutoResponse struct {
+	Trigger  string    `json:"trigger"`
+	Response string    `json:"response"`
+	Author   string    `json:"author,omitempty"`
+	Created  time.Time `json:"created"`
+}
+
+// GuildCustom holds the custom commands and auto-responses of a guild.
+type GuildCustom struct {
+	Commands      map[string]CustomCommand `json:"commands,omitempty"` // by lowercase name
+	AutoResponses []AutoResponse           `json:"auto_responses,omitempty"`
+}
+
+// renderTemplate fills in the placeholders of a custom response: {user} and
+// {username} for the author, {channel}, {args} for every argument and {1} to
+// {9} for single ones. Unknown placeholders are left as they are.
+func renderTemplate(text string, ctx *gl.CommandContext, args string) string {
+	words := strings.Fields(args)
+	pairs := []string{"{channel}", "<#" + ctx.ChannelID + ">", "{args}", strings.Join(words, " ")}
+	if ctx.Author != nil {
+		pairs = append(pairs, "{user}", "<@"+ctx.Author.ID+">", "{username}", ctx.Author.Username)
+	}
+	for n := 1; n <= 9; n++ {
+		var word string
+		if n <= len(words) {
+			word = words[n-1]
+		}
+		pairs = append(pairs, "{"+strconv.Itoa(n)+"}", word)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// matchesTrigger reports whether content mentions trigger as whole words,
+// ignoring case.
+func matchesTrigger(content, trigger string) bool {
+	content, trigger = strings.ToLower(content), strings.ToLower(trigger)
+	if trigger == "" {
+		return false
+	}
+
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for offset := 0; ; {
+		n := strings.Index(content[offset:], trigger)
+		if n < 0 {
+			return false
+		}
+		start, end := offset+n, offset+n+len(trigger)
+
+		before, after := ' ', ' '
+		if start > 0 {
+			before = []rune(content[:start])[len([]rune(content[:start]))-1]
+		}
+		if end < len(content) {
+			after = []rune(content[end:])[0]
+		}
+		if !isWord(before) && !isWord(after) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+// CustomCommands returns the custom commands and auto-responses of guildID,
+// both sorted by name.
+func (bs *BotService) CustomCommands(guildID string) ([]CustomCommand, []AutoResponse) {
+	gc, _ := bs.custom.Get(guildID)
+
+	commands := make([]CustomCommand, 0, len(gc.Commands))
+	for _, c := range gc.Commands {
+		commands = append(commands, c)
+	}
+	slices.SortFunc(commands, func(a, b CustomCommand) int { return cmp.Compare(a.Name, b.Name) })
+
+	responses := append([]AutoResponse{}, gc.AutoResponses...)
+	slices.SortFunc(responses, func(a, b AutoResponse) int { return cmp.Compare(a.Trigger, b.Trigger) })
+	return commands, responses
+}
+
+// SetCustomCommand adds or replaces a custom command of guildID. Its name
+// cannot shadow a bot command.
+func (bs *BotService) SetCustomCommand(guildID string, c CustomCommand) error {
+	c.Name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c.Name), bs.US.Config.Prefix))
+	if c.Name == "" || len([]rune(c.Name)) > maxCustomNameRunes || strings.ContainsFunc(c.Name, unicode.IsSpace) {
+		return ErrCustomName
+	}
+	if bs.getCommand(c.Name) != nil {
+		return ErrCustomReserved
+	}
+	if err := validResponse(c.Response); err != nil {
+		return err
+	}
+
+	var err error
+	updateErr := bs.custom.Update(guildID, func(gc *GuildCustom) {
+		if _, exists := gc.Commands[c.Name]; !exists && len(gc.Commands) >= maxCustomCommands {
+			err = ErrCustomLimit
+			return
+		}
+		// the stored map is shared with readers, so it is replaced instead of edited
+		gc.Commands = maps.Clone(gc.Commands)
+		if gc.Commands == nil {
+			gc.Commands = map[string]CustomCommand{}
+		}
+		if c.Created.IsZero() {
+			c.Created = time.Now()
+		}
+		gc.Commands[c.Name] = c
+	})
+	return cmp.Or(err, updateErr)
+}
+
+// DeleteCustomCommand removes the custom command name from guildID.
+func (bs *BotService) DeleteCustomCommand(guildID, name string) error {
+	name = strings.ToLower(name)
+	err := ErrCustomNotFound
+	updateErr := bs.custom.Update(guildID, func(gc *GuildCustom) {
+		if _, ok := gc.Commands[name]; ok {
+			gc.Commands = maps.Clone(gc.Commands)
+			delete(gc.Commands, name)
+			err = nil
+		}
+	})
+	return cmp.Or(err, updateErr)
+}
+
+// SetAutoResponse adds an auto-response to guildID, replacing the one with
+// the same trigger.
+func (bs *BotService) SetAutoResponse(guildID string, ar AutoResponse) error {
+	ar.Trigger = strings.TrimSpace(ar.Trigger)
+	if ar.Trigger == "" || len([]rune(ar.Trigger)) > gl.DiscordChoiceLimit {
+		return ErrCustomName
+	}
+	if err := validResponse(ar.Response); err != nil {
+		return err
+	}
+
+	var err error
+	updateErr := bs.custom.Update(guildID, func(gc *GuildCustom) {
+		if ar.Created.IsZero() {
+			ar.Created = time.Now()
+		}
+		gc.AutoResponses = slices.Clone(gc.AutoResponses)
+		n := slices.IndexFunc(gc.AutoResponses, func(a AutoResponse) bool { return strings.EqualFold(a.Trigger, ar.Trigger) })
+		switch {
+		case n >= 0:
+			gc.AutoResponses[n] = ar
+		case len(gc.AutoResponses) >= maxAutoResponses:
+			err = ErrAutoResponseLimit
+		default:
+			gc.AutoResponses = append(gc.AutoResponses, ar)
+		}
+	})
+	return cmp.Or(err, updateErr)
+}
+
+// DeleteAutoResponse removes the auto-response to trigger from guildID.
+func (bs *BotService) DeleteAutoResponse(guildID, trigger string) error {
+	err := ErrAutoResponseNotFnd
+	updateErr := bs.custom.Update(guildID, func(gc *GuildCustom) {
+		n := slices.IndexFunc(gc.AutoResponses, func(a AutoResponse) bool { return strings.EqualFold(a.Trigger, trigger) })
+		if n >= 0 {
+			gc.AutoResponses = slices.Delete(slices.Clone(gc.AutoResponses), n, n+1)
+			err = nil
+		}
+	})
+	return cmp.Or(err, updateErr)
+}
+
+func validResponse(response string) error {
+	if strings.TrimSpace(response) == "" || len([]rune(response)) > gl.DiscordEmbedDescriptionLimit {
+		return ErrCustomResponse
+	}
+	return nil
+}
+
+// customCooldown limits how often a member can run the custom commands of a
+// guild, which all count together.
+var customCooldown = gl.Cooldown{Uses: 3, Period: 10 * time.Second, Group: "custom"}
+
+// customCommand returns the custom command name of guildID, if there is one.
+func (bs *BotService) customCommand(guildID, name string) (CustomCommand, bool) {
+	if guildID == "" {
+		return CustomCommand{}, false
+	}
+
+	gc, _ := bs.custom.Get(guildID)
+	c, ok := gc.Commands[name]
+	return c, ok
+}
+
+// runCustomCommand answers with the response of c.
+func (bs *BotService) runCustomCommand(c CustomCommand, args string, ctx *gl.CommandContext) *gl.CommandResult {
+	response := bs.US.EmbedMessage(renderTemplate(c.Response, ctx, args))
+	response.AllowedMentions = invokerMentions(ctx)
+	return bs.US.ReplyMessage(response)
+}
+
+// invokerMentions only lets a custom response ping the user who invoked it,
+// so that {args} cannot be used to ping anyone else.
+func invokerMentions(ctx *gl.CommandContext) *discordgo.MessageAllowedMentions {
+	mentions := &discordgo.MessageAllowedMentions{}
+	if ctx.Author != nil {
+		mentions.Users = []string{ctx.Author.ID}
+	}
+	return mentions
+}
+
+// autoRespond sends the first auto-response of the guild whose trigger is
+// mentioned in m. Bots are ignored so that they cannot answer each other.
+func (bs *BotService) autoRespond(m *discordgo.MessageCreate) {
+	if m.GuildID == "" || m.Author.Bot {
+		return
+	}
+
+	gc, _ := bs.custom.Get(m.GuildID)
+	for _, ar := range gc.AutoResponses {
+		if !matchesTrigger(m.Content, ar.Trigger) {
+			continue
+		}
+
+		ctx := bs.US.MessageContext(m)
+		response := bs.US.EmbedMessage(renderTemplate(ar.Response, ctx, ""))
+		response.AllowedMentions = invokerMentions(ctx)
+		if _, err := bs.US.Session.ChannelMessageSendComplex(m.ChannelID, response); err != nil {
+			bs.logger.Error("could not send auto-response", "error", err)
+		}
+		return
+	}
+}
+
+// customError turns an error of the custom commands into a reply.
+func (bs *BotService) customError(err error, name string, ctx *gl.CommandContext) *gl.CommandResult {
+	switch {
+	case errors.Is(err, ErrCustomNotFound):
+		return ctx.UserError(gl.MsgCustomNotFoundFmt, name)
+	case errors.Is(err, ErrAutoResponseNotFnd):
+		return ctx.UserError(gl.MsgAutoResponseNotFoundFmt, name)
+	case errors.Is(err, ErrCustomReserved):
+		return ctx.UserError(gl.MsgCustomReservedFmt, name)
+	case errors.Is(err, ErrCustomName):
+		return ctx.UserError(gl.MsgCustomNameInvalidFmt, maxCustomNameRunes)
+	case errors.Is(err, ErrCustomResponse):
+		return ctx.UserError(gl.MsgCustomResponseLengthFmt, gl.DiscordEmbedDescriptionLimit)
+	case errors.Is(err, ErrCustomLimit):
+		return ctx.UserError(gl.MsgCustomLimitFmt, maxCustomCommands)
+	case errors.Is(err, ErrAutoResponseLimit):
+		return ctx.UserError(gl.MsgAutoResponseLimitFmt, maxAutoResponses)
+	}
+	return ctx.InternalError(errors.New("could not update custom commands: " + err.Error()))
+}
+
+// preview shortens a response to one line for listings.
+func preview(response string) string {
+	line, _, more := strings.Cut(response, "\n")
+	if runes := []rune(line); len(runes) > customPreviewRunes {
+		line, more = string(runes[:customPreviewRunes]), true
+	}
+	if more {
+		line += "…"
+	}
+	return line
+}
+
+func (bs *BotService) handleCustomAdd(ctx *gl.CommandContext) *gl.CommandResult {
+	if ctx.GuildID == "" {
+		return ctx.UserError(gl.MsgUseInServer)
+	}
+
+	name := ctx.Options.String("name")
+	err := bs.SetCustomCommand(ctx.GuildID, CustomCommand{Name: name, Response: ctx.Options.String("response"), Author: ctx.Author.ID})
+	if err != nil {
+		return bs.customError(err, name, ctx)
+	}
+	return ctx.Reply(gl.MsgCustomSavedFmt, bs.US.FormatCommand(strings.ToLower(name)))
+}
+
+func (bs *BotService) handleCustomRemove(ctx *gl.CommandContext) *gl.CommandResult {
+	if ctx.GuildID == "" {
+		return ctx.UserError(gl.MsgUseInServer)
+	}
+
+	name := ctx.Options.String("name")
+	if err := bs.DeleteCustomCommand(ctx.GuildID, name); err != nil {
+		return bs.customError(err, name, ctx)
+	}
+	return ctx.Reply(gl.MsgCustomDeletedFmt, bs.US.FormatCommand(strings.ToLower(name)))
+}
+
+func (bs *BotService) handleCustomList(ctx *gl.CommandContext) *gl.CommandResult {
+	if ctx.GuildID == "" {
+		return ctx.UserError(gl.MsgUseInServer)
+	}
+
+	commands, _ := bs.CustomCommands(ctx.GuildID)
+	if len(commands) == 0 {
+		return ctx.Reply(gl.MsgNoCustomCommands)
+	}
+
+	var out string
+	for _, c := range commands {
+		out += fmt.Sprintf(gl.MsgUnorderedList, ctx.T(gl.MsgCustomLineFmt, bs.US.FormatCommand(c.Name), preview(c.Response)))
+	}
+	return bs.US.Reply(out)
+}
+
+func (bs *BotService) handleAutoResponseAdd(ctx *gl.CommandContext) *gl.CommandResult {
+	if ctx.GuildID == "" {
+		return ctx.UserError(gl.MsgUseInServer)
+	}
+
+	trigger := ctx.Options.String("trigger")
+	err := bs.SetAutoResponse(ctx.GuildID, AutoResponse{Trigger: trigger, Response: ctx.Options.String("response"), Author: ctx.Author.ID})
+	if err != nil {
+		return bs.customError(err, trigger, ctx)
+	}
+	return ctx.Reply(gl.MsgAutoResponseSavedFmt, strings.TrimSpace(trigger))
+}
+
+func (bs *BotService) handleAutoResponseRemove(ctx *gl.CommandContext) *gl.CommandResult {
+	if ctx.GuildID == "" {
+		return ctx.UserError(gl.MsgUseInServer)
+	}
+
+	trigger := ctx.Options.String("trigger")
+	if err := bs.DeleteAutoResponse(ctx.GuildID, trigger); err != nil {
+		return bs.customError(err, trigger, ctx)
+	}
+	return ctx.Reply(gl.MsgAutoResponseDeletedFmt, trigger)
+}
+
+func (bs *BotService) handleAutoResponseList(ctx *gl.CommandContext) *gl.CommandResult {
+	if ctx.GuildID == "" {
+		return ctx.UserError(gl.MsgUseInServer)
+	}
+
+	_, responses := bs.CustomCommands(ctx.GuildID)
+	if len(responses) == 0 {
+		return ctx.Reply(gl.MsgNoAutoResponses)
+	}
+
+	var out string
+	for _, ar := range responses {
+		out += fmt.Sprintf(gl.MsgUnorderedList, ctx.T(gl.MsgCustomLineFmt, "`"+ar.Trigger+"`", preview(ar.Response)))
+	}
+	return bs.US.Reply(out)
+}
+
+// handleCustomAutocomplete suggests the custom commands of the guild.
+func (bs *BotService) handleCustomAutocomplete(focused string, i *discordgo.InteractionCreate) ([]*discordgo.ApplicationCommandOptionChoice, bool) {
+	commands, _ := bs.CustomCommands(i.GuildID)
+	return customChoices(commands, func(c CustomCommand) string { return c.Name }, focused), true
+}
+
+// handleAutoResponseAutocomplete suggests the auto-response triggers of the guild.
+func (bs *BotService) handleAutoResponseAutocomplete(focused string, i *discordgo.InteractionCreate) ([]*discordgo.ApplicationCommandOptionChoice, bool) {
+	_, responses := bs.CustomCommands(i.GuildID)
+	return customChoices(responses, func(ar AutoResponse) string { return ar.Trigger }, focused), true
+}
+
+func customChoices[T any](items []T, name func(T) string, focused string) []*discordgo.ApplicationCommandOptionChoice {
+	focused = strings.ToLower(focused)
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, item := range items {
+		n := name(item)
+		if strings.Contains(strings.ToLower(n), focused) && len(choices) < gl.DiscordMaxChoices {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: n, Value: n})
+		}
+	}
+	return choices
+}
@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"testing"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	ctx := &gl.CommandContext{
+		ChannelID: "10",
+		Author:    &discordgo.User{ID: "20", Username: "alice"},
+	}
+
+	cases := []struct {
+		text, args, want string
+	}{
+		{"hi {user} in {channel}", "", "hi <@20> in <#10>"},
+		{"{username} says {args}", "hello  there", "alice says hello there"},
+		{"{2} {1}{3}", "a b", "b a"},
+		{"{unknown}", "", "{unknown}"},
+	}
+	for _, tc := range cases {
+		if got := renderTemplate(tc.text, ctx, tc.args); got != tc.want {
+			t.Errorf("renderTemplate(%q, %q) = %q, want %q", tc.text, tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestMatchesTrigger(t *testing.T) {
+	cases := []struct {
+		content, trigger string
+		want             bool
+	}{
+		{"Good Morning everyone", "good morning", true},
+		{"hello!", "hello", true},
+		{"othello", "hello", false},
+		{"hellos and hello", "hello", true},
+		{"perché", "perch", false},
+		{"anything", "", false},
+	}
+	for _, tc := range cases {
+		if got := matchesTrigger(tc.content, tc.trigger); got != tc.want {
+			t.Errorf("matchesTrigger(%q, %q) = %v, want %v", tc.content, tc.trigger, got, tc.want)
+		}
+	}
+}
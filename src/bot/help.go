@@ -0,0 +1,240 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/bwmarrin/discordgo"
+)
+
+const helpInteraction = "help"
+
+// helpCategories are the modules listed by help, in the order of the menu.
+var helpCategories = []struct {
+	tag, label string
+}{
+	{gl.TagGeneral, gl.MsgHelpGeneral},
+	{gl.TagMusic, gl.MsgHelpMusic},
+	{gl.TagShoot, gl.MsgHelpShoot},
+}
+
+// permissionNames are the permissions that commands may require, as shown in Discord.
+var permissionNames = []struct {
+	perm int64
+	name string
+}{
+	{discordgo.PermissionAdministrator, "Administrator"},
+	{discordgo.PermissionManageGuild, "Manage Server"},
+	{discordgo.PermissionManageChannels, "Manage Channels"},
+	{discordgo.PermissionManageMessages, "Manage Messages"},
+	{discordgo.PermissionVoiceMoveMembers, "Move Members"},
+}
+
+// commandUsage describes the arguments of bc, such as "$play <query> [--position value]".
+func commandUsage(prefix, path string, bc gl.BotCommand) string {
+	usage := prefix + path
+	if bc.Handler == nil && bc.HasSubcommands() {
+		return usage + " <" + strings.Join(bc.SubcommandNames(), "|") + ">"
+	}
+
+	for _, opt := range bc.SlashOptions {
+		switch {
+		case opt.Flag && opt.Type == discordgo.ApplicationCommandOptionBoolean:
+			usage += " [--" + opt.Name + "]"
+		case opt.Flag:
+			usage += " [--" + opt.Name + " value]"
+		case opt.Required:
+			usage += " <" + opt.Name + ">"
+		default:
+			usage += " [" + opt.Name + "]"
+		}
+	}
+	return usage
+}
+
+// commandAliases returns the other names of the command at path.
+func commandAliases(path string, bc gl.BotCommand) []string {
+	var parent string
+	if n := strings.LastIndex(path, " "); n >= 0 {
+		parent = path[:n+1]
+	}
+
+	var aliases []string
+	for _, alias := range []string{bc.ShortCode, bc.Alias} {
+		if alias != "" {
+			aliases = append(aliases, parent+alias)
+		}
+	}
+	return aliases
+}
+
+// helpCategory lists the commands of one module, with a menu to switch to the
+// others. Disabled modules show the general commands instead.
+func (bs *BotService) helpCategory(ctx *gl.CommandContext, tag string) *discordgo.MessageSend {
+	if !bs.US.ModuleEnabled(ctx.GuildID, tag) {
+		tag = gl.TagGeneral // menus sent before the module was disabled still offer it
+	}
+
+	var lines, label string
+	var options []discordgo.SelectMenuOption
+	for _, c := range helpCategories {
+		if !bs.US.ModuleEnabled(ctx.GuildID, c.tag) || !bs.hasCommands(c.tag) {
+			continue
+		}
+		if c.tag == tag {
+			label = ctx.T(c.label)
+		}
+		options = append(options, discordgo.SelectMenuOption{Label: ctx.T(c.label), Value: c.tag, Default: c.tag == tag})
+	}
+
+	for _, command := range bs.commandNames {
+		if bc := bs.handlersMap[command]; bc.Tag == tag {
+			lines += fmt.Sprintf(gl.MsgUnorderedList, bs.US.FormatHelp(ctx.Locale(), command, bc))
+		}
+	}
+
+	msg := bs.US.EmbedMessage(lines)
+	msg.Embeds[0].Title = label
+	msg.Embeds[0].Footer = &discordgo.MessageEmbedFooter{Text: ctx.T(gl.MsgHelpFooterFmt, bs.US.Config.Prefix+"help <command>")}
+	if len(options) > 1 {
+		msg.Components = []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{CustomID: helpInteraction + ":", Placeholder: ctx.T(gl.MsgHelpCategory), Options: options},
+			}},
+		}
+	}
+	return msg
+}
+
+func (bs *BotService) hasCommands(tag string) bool {
+	for _, bc := range bs.handlersMap {
+		if bc.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// helpDetails describes a command or subcommand, given as the words after help.
+func (bs *BotService) helpDetails(ctx *gl.CommandContext, query string) *gl.CommandResult {
+	name, rest, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(query), bs.US.Config.Prefix), " ")
+	name = strings.ToLower(name)
+	if aliasTo, isAlias := bs.aliasMap[name]; isAlias {
+		name = aliasTo
+	}
+
+	top := bs.getCommand(name)
+	if top == nil || !bs.US.ModuleEnabled(ctx.GuildID, top.Tag) {
+		return ctx.UserError(gl.MsgUnknownCommand, bs.US.FormatCommand(name))
+	}
+	bc, path, _ := resolveSubcommand(top, name, rest)
+
+	msg := bs.US.EmbedMessage(ctx.T(bc.Help))
+	embed := msg.Embeds[0]
+	embed.Title = bs.US.Config.Prefix + path
+	addField := func(key string, lines []string) {
+		if len(lines) > 0 {
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: ctx.T(key), Value: strings.Join(lines, "\n")})
+		}
+	}
+
+	addField(gl.MsgHelpUsage, []string{"`" + commandUsage(bs.US.Config.Prefix, path, *bc) + "`"})
+
+	var arguments []string
+	for _, opt := range bc.SlashOptions {
+		line := fmt.Sprintf("`%s` - %s", opt.Name, ctx.T(opt.Description))
+		if len(opt.Choices) > 0 {
+			choices := make([]string, 0, len(opt.Choices))
+			for _, c := range opt.Choices {
+				choices = append(choices, fmt.Sprint(c.Value))
+			}
+			line += " (" + strings.Join(choices, ", ") + ")"
+		}
+		if !opt.Required {
+			line += ctx.T(gl.MsgHelpOptional)
+		}
+		arguments = append(arguments, line)
+	}
+	addField(gl.MsgHelpArguments, arguments)
+
+	var subcommands []string
+	for _, sub := range bc.SubcommandNames() {
+		subcommands = append(subcommands, ctx.T(gl.MsgHelpFmt, bs.US.FormatCommand(path+" "+sub), ctx.T(bc.Subcommands[sub].Help)))
+	}
+	addField(gl.MsgHelpSubcommands, subcommands)
+
+	var aliases []string
+	for _, alias := range commandAliases(path, *bc) {
+		aliases = append(aliases, bs.US.FormatCommand(alias))
+	}
+	addField(gl.MsgHelpAliases, aliases)
+
+	var examples []string
+	for _, example := range bc.Examples {
+		examples = append(examples, bs.US.FormatCommand(path+" "+example))
+	}
+	addField(gl.MsgHelpExamples, examples)
+
+	var permissions []string
+	perms := top.Permissions | bc.Permissions
+	for _, p := range permissionNames {
+		if perms&p.perm != 0 {
+			permissions = append(permissions, ctx.T(p.name))
+		}
+	}
+	addField(gl.MsgHelpPermissions, permissions)
+
+	for _, c := range helpCategories {
+		if c.tag == top.Tag {
+			embed.Footer = &discordgo.MessageEmbedFooter{Text: ctx.T(c.label)}
+		}
+	}
+	return bs.US.ReplyMessage(msg)
+}
+
+func (bs *BotService) handleHelp(ctx *gl.CommandContext) *gl.CommandResult {
+	if query := ctx.Options.String("command"); query != "" {
+		return bs.helpDetails(ctx, query)
+	}
+	return bs.US.ReplyMessage(bs.helpCategory(ctx, gl.TagGeneral))
+}
+
+// handleHelpCategory switches the help message to the module picked in its menu.
+func (bs *BotService) handleHelpCategory(arg string, ctx *gl.CommandContext) *gl.CommandResult {
+	i := ctx.Interaction
+	values := i.MessageComponentData().Values
+	if len(values) == 0 {
+		return ctx.UserError(gl.MsgUnknownCommand, arg)
+	}
+
+	response := bs.US.EmbedToResponse(bs.helpCategory(ctx, values[0]))
+	response.Type = discordgo.InteractionResponseUpdateMessage
+	if err := bs.US.Session.InteractionRespond(i.Interaction, response); err != nil {
+		bs.logger.Error("could not update help message", "error", err)
+	}
+	return nil
+}
+
+// handleHelpAutocomplete suggests commands and subcommands, such as "playlist add".
+func (bs *BotService) handleHelpAutocomplete(focused string, i *discordgo.InteractionCreate) ([]*discordgo.ApplicationCommandOptionChoice, bool) {
+	focused = strings.ToLower(focused)
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	add := func(path string) {
+		if strings.Contains(path, focused) && len(choices) < gl.DiscordMaxChoices {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: path, Value: path})
+		}
+	}
+
+	for _, command := range bs.commandNames {
+		bc := bs.handlersMap[command]
+		if !bs.US.ModuleEnabled(i.GuildID, bc.Tag) {
+			continue
+		}
+		add(command)
+		for _, sub := range bc.SubcommandNames() {
+			add(command + " " + sub)
+		}
+	}
+	return choices, true
+}
@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"slices"
+	"testing"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCommandUsage(t *testing.T) {
+	handler := func(*gl.CommandContext) *gl.CommandResult { return nil }
+	cases := []struct {
+		path string
+		bc   gl.BotCommand
+		want string
+	}{
+		{"skip", gl.BotCommand{Handler: handler}, "$skip"},
+		{"play", gl.BotCommand{Handler: handler, SlashOptions: []gl.SlashOption{
+			{Name: "query", Required: true},
+			{Name: "position", Flag: true},
+		}}, "$play <query> [--position value]"},
+		{"lyrics", gl.BotCommand{Handler: handler, SlashOptions: []gl.SlashOption{
+			{Name: "query"},
+			{Name: "live", Type: discordgo.ApplicationCommandOptionBoolean, Flag: true},
+		}}, "$lyrics [query] [--live]"},
+		{"volume", gl.BotCommand{Handler: handler, SlashOptions: []gl.SlashOption{{Name: "level"}}}, "$volume [level]"},
+		{"top", gl.BotCommand{Subcommands: map[string]gl.BotCommand{"tracks": {}, "artists": {}}}, "$top <artists|tracks>"},
+	}
+	for _, tc := range cases {
+		if got := commandUsage("$", tc.path, tc.bc); got != tc.want {
+			t.Errorf("commandUsage(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestCommandAliases(t *testing.T) {
+	cases := []struct {
+		path string
+		bc   gl.BotCommand
+		want []string
+	}{
+		{"previous", gl.BotCommand{ShortCode: "b", Alias: "back"}, []string{"b", "back"}},
+		{"playlist save", gl.BotCommand{ShortCode: "s"}, []string{"playlist s"}},
+		{"history", gl.BotCommand{}, nil},
+	}
+	for _, tc := range cases {
+		if got := commandAliases(tc.path, tc.bc); !slices.Equal(got, tc.want) {
+			t.Errorf("commandAliases(%q) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"maps"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+)
+
+// invocation is a command about to run, with what the middlewares check.
+type invocation struct {
+	ctx         *gl.CommandContext
+	name        string // top-level command, whose subcommands share its cooldown unless grouped
+	shown       string // how the command is written on its surface
+	tag         string
+	permissions int64 // required along the whole path
+	cooldown    gl.Cooldown
+	handler     func(*gl.CommandContext) *gl.CommandResult
+}
+
+// middleware wraps the run of a command; it either answers by itself or
+// returns the result of next.
+type middleware func(inv *invocation, next func() *gl.CommandResult) *gl.CommandResult
+
+// CommandStats are counted for every command since the bot started.
+type CommandStats struct {
+	Runs       int   `json:"runs"`
+	UserErrors int   `json:"user_errors"`
+	Failures   int   `json:"failures"`
+	TotalMS    int64 `json:"total_ms"`
+}
+
+type commandMetrics struct {
+	mu    sync.Mutex
+	stats map[string]CommandStats // by command path
+}
+
+func (bs *BotService) initMiddlewares() {
+	bs.metrics = &commandMetrics{stats: map[string]CommandStats{}}
+	bs.middlewares = []middleware{
+		bs.logCommands,
+		bs.countCommands,
+		bs.recoverPanics,
+		bs.checkModule,
+		bs.checkPermissions,
+		bs.checkCooldowns,
+	}
+}
+
+// run passes inv through the middlewares and then to its handler.
+func (bs *BotService) run(inv *invocation) *gl.CommandResult {
+	var step func(n int) *gl.CommandResult
+	step = func(n int) *gl.CommandResult {
+		if n == len(bs.middlewares) {
+			return inv.handler(inv.ctx)
+		}
+		return bs.middlewares[n](inv, func() *gl.CommandResult { return step(n + 1) })
+	}
+	return step(0)
+}
+
+// CommandMetrics returns a copy of the stats of every command that ran.
+func (bs *BotService) CommandMetrics() map[string]CommandStats {
+	bs.metrics.mu.Lock()
+	defer bs.metrics.mu.Unlock()
+	return maps.Clone(bs.metrics.stats)
+}
+
+func (bs *BotService) logCommands(inv *invocation, next func() *gl.CommandResult) *gl.CommandResult {
+	start := time.Now()
+	r := next()
+	bs.logger.Debug("Ran command", "command", inv.ctx.Command, "surface", inv.ctx.Surface, "user", inv.ctx.Author.ID, "took", time.Since(start))
+	if r != nil && r.Kind == gl.ResultInternalError {
+		bs.logger.Warn("command failed", "command", inv.ctx.Command, "surface", inv.ctx.Surface)
+	}
+	return r
+}
+
+func (bs *BotService) countCommands(inv *invocation, next func() *gl.CommandResult) *gl.CommandResult {
+	start := time.Now()
+	r := next()
+
+	bs.metrics.mu.Lock()
+	defer bs.metrics.mu.Unlock()
+	s := bs.metrics.stats[inv.ctx.Command]
+	s.Runs++
+	s.TotalMS += time.Since(start).Milliseconds()
+	if r != nil {
+		switch r.Kind {
+		case gl.ResultUserError:
+			s.UserErrors++
+		case gl.ResultInternalError:
+			s.Failures++
+		}
+	}
+	bs.metrics.stats[inv.ctx.Command] = s
+	return r
+}
+
+// recoverPanics answers with an internal error when a command panics, so
+// that one broken handler cannot take the bot down.
+func (bs *BotService) recoverPanics(inv *invocation, next func() *gl.CommandResult) (r *gl.CommandResult) {
+	defer func() {
+		if err := recover(); err != nil {
+			bs.logger.Error("command panicked", "command", inv.ctx.Command, "surface", inv.ctx.Surface, "error", err, "stack", string(debug.Stack()))
+			r = inv.ctx.InternalError(nil)
+		}
+	}()
+	return next()
+}
+
+func (bs *BotService) checkModule(inv *invocation, next func() *gl.CommandResult) *gl.CommandResult {
+	if !bs.US.ModuleEnabled(inv.ctx.GuildID, inv.tag) {
+		return inv.ctx.UserError(gl.MsgModuleDisabled)
+	}
+	return next()
+}
+
+func (bs *BotService) checkPermissions(inv *invocation, next func() *gl.CommandResult) *gl.CommandResult {
+	if !bs.US.HasPermissions(inv.ctx.Member, inv.ctx.ChannelID, inv.ctx.Author.ID, inv.permissions) {
+		return inv.ctx.UserError(gl.MsgMissingPerms)
+	}
+	return next()
+}
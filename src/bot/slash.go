@@ -1,66 +1,164 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	gl "github.com/birabittoh/disgord/src/globals"
 	"github.com/bwmarrin/discordgo"
 )
 
+// registrationDocument remembers where commands were registered between runs.
+const registrationDocument = "commands"
+
+type registration struct {
+	DevGuildID string `json:"dev_guild_id,omitempty"`
+}
+
 /*
-registerSlashCommands efficiently registers all commands in handlersMap as Discord slash commands.
-It only deletes obsolete commands, creates new ones, and updates changed ones.
+registerSlashCommands efficiently registers all commands in handlersMap as Discord slash commands,
+along with the context menu commands in contextMap.
+Depending on the configured scope they are registered globally, per guild or in the dev guild only;
+global commands are cleared in the latter two cases, while guilds are reconciled as they are joined.
 */
 func (bs *BotService) registerSlashCommands() error {
-	existingCommands, err := bs.US.Session.ApplicationCommands(bs.US.Session.State.User.ID, "")
-	if err != nil {
+	bs.logger.Debug("Slash commands registration started")
+
+	if err := bs.clearPreviousDevGuild(); err != nil {
 		return err
 	}
 
-	bs.logger.Debug("Slash commands registration started")
+	if bs.guildScoped() {
+		if err := bs.syncCommands("", nil); err != nil {
+			return err
+		}
+		if bs.US.Config.DevGuildID != "" {
+			return bs.syncGuildCommands(bs.US.Config.DevGuildID)
+		}
+		return nil
+	}
 
+	return bs.syncCommands("", bs.desiredCommands(""))
+}
+
+// clearPreviousDevGuild removes the commands of the dev guild of the last run
+// when DEV_GUILD_ID changed; guilds that still need them get them back once
+// they are reconciled.
+func (bs *BotService) clearPreviousDevGuild() error {
+	var last registration
+	if err := bs.US.Store.Load(registrationDocument, &last); err != nil {
+		return errors.New("could not load command registration: " + err.Error())
+	}
+
+	devGuildID := bs.US.Config.DevGuildID
+	if last.DevGuildID == devGuildID {
+		return nil
+	}
+	if last.DevGuildID != "" {
+		// the bot may have left that guild, which is just as good
+		if err := bs.syncCommands(last.DevGuildID, nil); err != nil {
+			bs.logger.Warn("could not clear commands of the previous dev guild", "guild", last.DevGuildID, "error", err)
+		} else {
+			bs.logger.Info("Cleared commands of the previous dev guild", "guild", last.DevGuildID)
+		}
+	}
+	return bs.US.Store.Save(registrationDocument, registration{DevGuildID: devGuildID})
+}
+
+// guildScoped reports whether commands are registered per guild rather than globally.
+func (bs *BotService) guildScoped() bool {
+	return bs.US.Config.SlashCommandsScope == "guild" || bs.US.Config.DevGuildID != ""
+}
+
+// syncGuildCommands reconciles the commands of a single guild with its enabled modules.
+func (bs *BotService) syncGuildCommands(guildID string) error {
+	if bs.US.Config.DevGuildID != "" && guildID != bs.US.Config.DevGuildID {
+		return nil
+	}
+
+	var desired map[string]*discordgo.ApplicationCommand
+	if bs.guildScoped() {
+		desired = bs.desiredCommands(guildID)
+	}
+	return bs.syncCommands(guildID, desired)
+}
+
+// desiredCommands lists the slash commands that should exist for guildID,
+// leaving out disabled modules. An empty guildID means global commands.
+func (bs *BotService) desiredCommands(guildID string) map[string]*discordgo.ApplicationCommand {
 	desired := map[string]*discordgo.ApplicationCommand{}
-	if !bs.US.Config.DisableSlashCommands {
-		for name, botCommand := range bs.handlersMap {
-			options := []*discordgo.ApplicationCommandOption{}
-			for _, opt := range botCommand.SlashOptions {
-				options = append(options, &discordgo.ApplicationCommandOption{
-					Type:        opt.Type,
-					Name:        opt.Name,
-					Description: opt.Description,
-					Required:    opt.Required,
-				})
-			}
+	if bs.US.Config.DisableSlashCommands {
+		return desired
+	}
 
-			cmd := &discordgo.ApplicationCommand{
-				Name:        name,
-				Description: botCommand.Help,
-				Options:     options,
-			}
+	for name, botCommand := range bs.handlersMap {
+		if !bs.US.ModuleEnabled(guildID, botCommand.Tag) {
+			continue
+		}
 
-			desired[name] = cmd
+		options := bs.slashCommandOptions(botCommand)
+		names, descriptions := bs.localizations(name, botCommand.Help)
+		var perms *int64
+		if botCommand.Permissions != 0 {
+			perms = &botCommand.Permissions
+		}
 
-			// Register alias as a separate command if present and non-empty
-			if botCommand.Alias != "" {
-				aliasCmd := &discordgo.ApplicationCommand{
-					Name:        botCommand.Alias,
-					Description: botCommand.Help,
-					Options:     options,
-				}
-				desired[botCommand.Alias] = aliasCmd
+		cmd := &discordgo.ApplicationCommand{
+			Name:                     name,
+			NameLocalizations:        &names,
+			Type:                     discordgo.ChatApplicationCommand,
+			Description:              botCommand.Help,
+			DescriptionLocalizations: &descriptions,
+			Options:                  options,
+			DefaultMemberPermissions: perms,
+		}
+
+		desired[name] = cmd
+
+		// Register alias as a separate command if present and non-empty
+		if botCommand.Alias != "" {
+			aliasNames, _ := bs.localizations(botCommand.Alias, "")
+			aliasCmd := &discordgo.ApplicationCommand{
+				Name:                     botCommand.Alias,
+				NameLocalizations:        &aliasNames,
+				Type:                     discordgo.ChatApplicationCommand,
+				Description:              botCommand.Help,
+				DescriptionLocalizations: &descriptions,
+				Options:                  options,
+				DefaultMemberPermissions: perms,
 			}
+			desired[botCommand.Alias] = aliasCmd
+		}
+	}
+
+	for name, contextCommand := range bs.contextMap {
+		if bs.US.ModuleEnabled(guildID, contextCommand.Tag) {
+			names, _ := bs.localizations(name, "")
+			desired[name] = &discordgo.ApplicationCommand{Name: name, NameLocalizations: &names, Type: contextCommand.Type}
 		}
 	}
+	return desired
+}
+
+// syncCommands makes the registered commands of guildID ("" for global) match desired.
+// It only deletes obsolete commands, creates new ones, and updates changed ones.
+func (bs *BotService) syncCommands(guildID string, desired map[string]*discordgo.ApplicationCommand) error {
+	existingCommands, err := bs.US.Session.ApplicationCommands(bs.US.Session.State.User.ID, guildID)
+	if err != nil {
+		return err
+	}
 
-	// Delete obsolete commands
+	// Delete obsolete commands, including ones whose type changed
 	for _, cmd := range existingCommands {
-		if _, ok := desired[cmd.Name]; !ok {
-			err := bs.US.Session.ApplicationCommandDelete(bs.US.Session.State.User.ID, "", cmd.ID)
+		if d, ok := desired[cmd.Name]; !ok || d.Type != cmd.Type {
+			err := bs.US.Session.ApplicationCommandDelete(bs.US.Session.State.User.ID, guildID, cmd.ID)
 			if err != nil {
 				return err
 			}
-			bs.logger.Info("Deleted obsolete command", "command", cmd.Name)
+			bs.logger.Info("Deleted obsolete command", "command", cmd.Name, "guild", guildID)
 		}
 	}
 
@@ -68,68 +166,381 @@ func (bs *BotService) registerSlashCommands() error {
 	for name, desiredCmd := range desired {
 		var found *discordgo.ApplicationCommand
 		for _, cmd := range existingCommands {
-			if cmd.Name == name {
+			if cmd.Name == name && cmd.Type == desiredCmd.Type {
 				found = cmd
 				break
 			}
 		}
 		if found == nil {
-			created, err := bs.US.Session.ApplicationCommandCreate(bs.US.Config.ApplicationID, "", desiredCmd)
+			created, err := bs.US.Session.ApplicationCommandCreate(bs.US.Config.ApplicationID, guildID, desiredCmd)
 			if err != nil {
 				return err
 			}
-			bs.logger.Info("Created new command", "command", created.Name)
+			bs.logger.Info("Created new command", "command", created.Name, "guild", guildID)
 		} else {
 			// Compare and update if changed
-			changed := found.Description != desiredCmd.Description || len(found.Options) != len(desiredCmd.Options)
-			if !changed {
-				for i, opt := range found.Options {
-					dOpt := desiredCmd.Options[i]
-					if opt.Name != dOpt.Name || opt.Description != dOpt.Description || opt.Type != dOpt.Type || opt.Required != dOpt.Required {
-						changed = true
-						break
-					}
-				}
-			}
+			changed := found.Description != desiredCmd.Description || !optionsEqual(found.Options, desiredCmd.Options) || !permissionsEqual(found.DefaultMemberPermissions, desiredCmd.DefaultMemberPermissions) ||
+				!localizationsEqual(found.NameLocalizations, desiredCmd.NameLocalizations) || !localizationsEqual(found.DescriptionLocalizations, desiredCmd.DescriptionLocalizations)
 			if changed {
-				updated, err := bs.US.Session.ApplicationCommandEdit(bs.US.Config.ApplicationID, "", found.ID, desiredCmd)
+				updated, err := bs.US.Session.ApplicationCommandEdit(bs.US.Config.ApplicationID, guildID, found.ID, desiredCmd)
 				if err != nil {
 					return err
 				}
-				bs.logger.Info("Updated command", "command", updated.Name, "id", updated.ID)
+				bs.logger.Info("Updated command", "command", updated.Name, "id", updated.ID, "guild", guildID)
 			}
 		}
 	}
 
-	bs.logger.Info("Slash commands registration completed")
+	bs.logger.Info("Slash commands registration completed", "guild", guildID)
 	return nil
 }
 
-// slashHandler adds a handler for Discord interactions, routing them to the appropriate command handlers.
-func (bs *BotService) slashHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	switch i.Type {
-	case discordgo.InteractionMessageComponent:
-		customID := i.MessageComponentData().CustomID
-		splitResult := strings.SplitN(customID, ":", 2)
-		if len(splitResult) != 2 {
-			response := bs.US.EmbedToResponse(bs.US.EmbedMessage(gl.MsgUnknownCommand))
-			s.InteractionRespond(i.Interaction, response)
-			return
+func permissionsEqual(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// localizationsEqual treats missing localizations as empty ones.
+func localizationsEqual(a, b *map[discordgo.Locale]string) bool {
+	var ma, mb map[discordgo.Locale]string
+	if a != nil {
+		ma = *a
+	}
+	if b != nil {
+		mb = *b
+	}
+	return maps.Equal(ma, mb)
+}
+
+// localizations returns the translations of a command or option name and of
+// its description from the message catalogs, keyed by Discord locale.
+func (bs *BotService) localizations(name, description string) (names, descriptions map[discordgo.Locale]string) {
+	convert := func(l map[string]string) map[discordgo.Locale]string {
+		if len(l) == 0 {
+			return nil
+		}
+		converted := make(map[discordgo.Locale]string, len(l))
+		for locale, text := range l {
+			converted[discordgo.Locale(locale)] = text
+		}
+		return converted
+	}
+
+	names = convert(bs.US.Translator.Localizations(name, true))
+	if description != "" {
+		descriptions = convert(bs.US.Translator.Localizations(description, false))
+	}
+	return names, descriptions
+}
+
+// guildCreateHandler reconciles the commands of a guild when it becomes available.
+// In global scope this clears commands left over from a previous guild scope.
+func (bs *BotService) guildCreateHandler(s *discordgo.Session, g *discordgo.GuildCreate) {
+	if err := bs.syncGuildCommands(g.ID); err != nil {
+		bs.logger.Error("could not register guild slash commands", "guild", g.ID, "error", err)
+	}
+}
+
+// guildDeleteHandler forgets a guild the bot was removed from. Discord drops
+// its guild commands on its own; outages are reported as unavailable and ignored.
+func (bs *BotService) guildDeleteHandler(s *discordgo.Session, g *discordgo.GuildDelete) {
+	if g.Unavailable {
+		return
+	}
+
+	bs.logger.Info("Removed from guild", "guild", g.ID)
+	if bs.MS != nil {
+		bs.MS.DeleteQueue(g.ID)
+	}
+}
+
+// slashCommandOptions builds the Discord options of bc, nesting its subcommands
+// and groups, along with their translations. Discord does not allow subcommands
+// next to options, so a command with options of its own only offers those.
+func (bs *BotService) slashCommandOptions(bc gl.BotCommand) []*discordgo.ApplicationCommandOption {
+	options := []*discordgo.ApplicationCommandOption{}
+	if !bc.HasSubcommands() || len(bc.SlashOptions) > 0 {
+		for _, opt := range bc.SlashOptions {
+			option := opt.ApplicationCommandOption()
+			option.NameLocalizations, option.DescriptionLocalizations = bs.localizations(opt.Name, opt.Description)
+			options = append(options, option)
+		}
+		return options
+	}
+
+	for _, name := range bc.SubcommandNames() {
+		sub := bc.Subcommands[name]
+		optType := discordgo.ApplicationCommandOptionSubCommand
+		if sub.HasSubcommands() {
+			optType = discordgo.ApplicationCommandOptionSubCommandGroup
+		}
+
+		names, descriptions := bs.localizations(name, sub.Help)
+		options = append(options, &discordgo.ApplicationCommandOption{
+			Type:                     optType,
+			Name:                     name,
+			NameLocalizations:        names,
+			Description:              sub.Help,
+			DescriptionLocalizations: descriptions,
+			Options:                  bs.slashCommandOptions(sub),
+		})
+	}
+	return options
+}
+
+// resolveSlashSubcommand follows the subcommand options of an interaction down
+// to the command that handles it, returning that command's own options.
+func resolveSlashSubcommand(bc *gl.BotCommand, path string, options []*discordgo.ApplicationCommandInteractionDataOption) (*gl.BotCommand, string, []*discordgo.ApplicationCommandInteractionDataOption) {
+	for len(options) == 1 && bc.HasSubcommands() {
+		opt := options[0]
+		if opt.Type != discordgo.ApplicationCommandOptionSubCommand && opt.Type != discordgo.ApplicationCommandOptionSubCommandGroup {
+			break
 		}
 
-		cmd, arg := splitResult[0], splitResult[1]
-		bi, found := bs.interactionsMap[cmd]
-		if !found {
-			response := bs.US.EmbedToResponse(bs.US.EmbedMessage(gl.MsgUnknownCommand))
-			s.InteractionRespond(i.Interaction, response)
+		sub, ok := bc.Subcommands[opt.Name]
+		if !ok {
+			break
+		}
+		bc, path, options = &sub, path+" "+opt.Name, opt.Options
+	}
+	return bc, path, options
+}
+
+// optionsEqual reports whether two option lists would register the same slash command.
+func optionsEqual(a, b []*discordgo.ApplicationCommandOption) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i, opt := range a {
+		dOpt := b[i]
+		if opt.Name != dOpt.Name || opt.Description != dOpt.Description || opt.Type != dOpt.Type || opt.Required != dOpt.Required || opt.Autocomplete != dOpt.Autocomplete {
+			return false
+		}
+		if !maps.Equal(opt.NameLocalizations, dOpt.NameLocalizations) || !maps.Equal(opt.DescriptionLocalizations, dOpt.DescriptionLocalizations) {
+			return false
+		}
+		if opt.MaxValue != dOpt.MaxValue || (opt.MinValue == nil) != (dOpt.MinValue == nil) || (opt.MinValue != nil && *opt.MinValue != *dOpt.MinValue) {
+			return false
+		}
+		if !slices.Equal(opt.ChannelTypes, dOpt.ChannelTypes) || len(opt.Choices) != len(dOpt.Choices) {
+			return false
+		}
+		if !optionsEqual(opt.Options, dOpt.Options) {
+			return false
+		}
+		for j, c := range opt.Choices {
+			if c.Name != dOpt.Choices[j].Name || fmt.Sprint(c.Value) != fmt.Sprint(dOpt.Choices[j].Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// deferResponse acknowledges an interaction right away, showing the bot as
+// thinking until respond edits the response. It reports whether it succeeded.
+// The deferred response is public: ephemeral results are sent as follow-ups.
+func (bs *BotService) deferResponse(i *discordgo.InteractionCreate) bool {
+	err := bs.US.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		bs.logger.Error("could not defer interaction response", "error", err)
+		return false
+	}
+	return true
+}
+
+// respond sends r as the response to the interaction of ctx, editing the
+// deferred response when deferred is set. The sent message is only returned
+// for edits.
+func (bs *BotService) respond(ctx *gl.CommandContext, deferred bool, r *gl.CommandResult) (*discordgo.Message, error) {
+	i := ctx.Interaction
+	if r != nil {
+		ctx.LocalizeResult(r)
+	}
+
+	var err error
+	switch {
+	case deferred && r == nil:
+		err = bs.US.Session.InteractionResponseDelete(i.Interaction)
+	case deferred && r.Ephemeral:
+		// a deferred response cannot become ephemeral, replace it with a follow-up
+		bs.US.Session.InteractionResponseDelete(i.Interaction)
+		_, err = bs.US.Session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Embeds:     r.Message.Embeds,
+			Components: r.Message.Components,
+			Files:      r.Message.Files,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		})
+	case deferred:
+		var sent *discordgo.Message
+		sent, err = bs.US.Session.InteractionResponseEdit(i.Interaction, bs.US.EmbedToWebhookEdit(r.Message))
+		if err == nil {
+			if r.Sent != nil {
+				r.Sent(sent)
+			}
+			return sent, nil
+		}
+	case r == nil:
+		return nil, nil
+	default:
+		err = bs.US.Session.InteractionRespond(i.Interaction, bs.US.ResultToResponse(r))
+		if err == nil && r.Sent != nil {
+			var sent *discordgo.Message
+			if sent, err = bs.US.Session.InteractionResponse(i.Interaction); err == nil {
+				r.Sent(sent)
+			}
+		}
+	}
+
+	if err != nil {
+		bs.logger.Error("could not respond to interaction", "error", err)
+	}
+	return nil, err
+}
+
+// handleContextCommand runs a user or message context menu command on its target.
+func (bs *BotService) handleContextCommand(i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	ctx := bs.US.InteractionContext(gl.SurfaceContextMenu, i)
+	ctx.Command = data.Name
+	cc, found := bs.contextMap[data.Name]
+	if !found || cc.Type != data.CommandType {
+		bs.respond(ctx, false, ctx.UserError(gl.MsgUnknownCommand, data.Name))
+		return
+	}
+
+	target := data.TargetID
+	if cc.Type == discordgo.MessageApplicationCommand {
+		msg := data.Resolved.Messages[data.TargetID]
+		if msg == nil || strings.TrimSpace(msg.Content) == "" {
+			bs.respond(ctx, false, ctx.UserError(gl.MsgNoKeywords))
 			return
 		}
+		target = msg.Content
+	}
+	ctx.Options[cc.Option] = target
+
+	var deferred bool
+	r := bs.run(&invocation{
+		ctx:      ctx,
+		name:     data.Name,
+		shown:    "`" + data.Name + "`",
+		tag:      cc.Tag,
+		cooldown: cc.Cooldown,
+		handler:  bs.deferringHandler(i, cc.Slow, &deferred, cc.Handler),
+	})
+	bs.respond(ctx, deferred, r)
+}
+
+// deferringHandler defers the response of slow commands right before handler
+// runs, so that the middlewares can still answer at once, and records it in
+// deferred.
+func (bs *BotService) deferringHandler(i *discordgo.InteractionCreate, slow bool, deferred *bool, handler func(*gl.CommandContext) *gl.CommandResult) func(*gl.CommandContext) *gl.CommandResult {
+	return func(ctx *gl.CommandContext) *gl.CommandResult {
+		if slow {
+			if !bs.deferResponse(i) {
+				return nil
+			}
+			*deferred = true
+		}
+		return handler(ctx)
+	}
+}
+
+// handleInteraction routes a button press or modal submission, whose custom ID
+// has the form "interaction:arg", through the middlewares to its handler in
+// interactionsMap.
+func (bs *BotService) handleInteraction(i *discordgo.InteractionCreate, customID string) {
+	ctx := bs.US.InteractionContext(gl.SurfaceComponent, i)
+	cmd, arg, ok := strings.Cut(customID, ":")
+	bi, found := bs.interactionsMap[cmd]
+	if !ok || !found {
+		bs.respond(ctx, false, ctx.UserError(gl.MsgUnknownCommand, customID))
+		return
+	}
+	ctx.Command = cmd
+
+	var deferred bool
+	r := bs.run(&invocation{
+		ctx:      ctx,
+		name:     cmd,
+		shown:    "`" + cmd + "`",
+		tag:      bi.Tag,
+		cooldown: bi.Cooldown,
+		handler: bs.deferringHandler(i, bi.Slow, &deferred, func(ctx *gl.CommandContext) *gl.CommandResult {
+			return bi.Handler(arg, ctx)
+		}),
+	})
+
+	msg, err := bs.respond(ctx, deferred, r)
+	if err == nil && r != nil && cmd == "search" {
+		bs.trackSearchMessage(i, msg)
+	}
+}
 
-		response := bi.Handler(arg, i)
-		if response != nil {
-			resp := bs.US.EmbedToResponse(response)
-			s.InteractionRespond(i.Interaction, resp)
+// showModal responds to an interaction with a modal form.
+func (bs *BotService) showModal(i *discordgo.InteractionCreate, form *discordgo.InteractionResponseData) {
+	err := bs.US.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: form,
+	})
+	if err != nil {
+		bs.logger.Error("could not show modal", "error", err)
+	}
+}
+
+// localizeForm translates the title and labels of a modal built by gl.ModalForm.
+func (bs *BotService) localizeForm(form *discordgo.InteractionResponseData, locale string) *discordgo.InteractionResponseData {
+	form.Title = bs.US.Translator.Message(locale, form.Title)
+	for n, c := range form.Components {
+		row, ok := c.(discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for k, rc := range row.Components {
+			if input, ok := rc.(discordgo.TextInput); ok {
+				input.Label = bs.US.Translator.Message(locale, input.Label)
+				row.Components[k] = input
+			}
 		}
+		form.Components[n] = row
+	}
+	return form
+}
+
+// trackSearchMessage remembers the message holding search results, so the next
+// search by the same user can clean it up. msg is nil unless the response was edited.
+func (bs *BotService) trackSearchMessage(i *discordgo.InteractionCreate, msg *discordgo.Message) {
+	if bs.MS == nil {
+		return
+	}
+
+	var err error
+	if msg == nil {
+		msg, err = bs.US.Session.InteractionResponse(i.Interaction)
+	}
+	if err != nil || msg == nil {
+		return
+	}
+
+	if author := gl.InteractionUser(i); author != nil {
+		bs.MS.SetSearchMessageID(i.ChannelID, author.ID, msg.ID)
+	}
+}
+
+// slashHandler adds a handler for Discord interactions, routing them to the appropriate command handlers.
+func (bs *BotService) slashHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionMessageComponent:
+		bs.handleInteraction(i, i.MessageComponentData().CustomID)
+		return
+
+	case discordgo.InteractionModalSubmit:
+		bs.handleInteraction(i, i.ModalSubmitData().CustomID)
 		return
 
 	case discordgo.InteractionApplicationCommand:
@@ -137,45 +548,111 @@ func (bs *BotService) slashHandler(s *discordgo.Session, i *discordgo.Interactio
 			return
 		}
 
-		name := i.ApplicationCommandData().Name
+		data := i.ApplicationCommandData()
+		if data.CommandType == discordgo.UserApplicationCommand || data.CommandType == discordgo.MessageApplicationCommand {
+			bs.handleContextCommand(i, data)
+			return
+		}
+
+		name := data.Name
 		if aliasTo, isAlias := bs.aliasMap[name]; isAlias {
 			name = aliasTo
 		}
 
+		ctx := bs.US.InteractionContext(gl.SurfaceSlash, i)
 		bc := bs.getCommand(name)
 		if bc == nil {
-			response := bs.US.EmbedToResponse(bs.US.EmbedMessage(fmt.Sprintf(gl.MsgUnknownCommand, name)))
-			s.InteractionRespond(i.Interaction, response)
+			bs.respond(ctx, false, ctx.UserError(gl.MsgUnknownCommand, name))
 			return
 		}
 
-		var args []string
-		for _, opt := range i.ApplicationCommandData().Options {
-			if opt.Type == discordgo.ApplicationCommandOptionString {
-				args = append(args, opt.StringValue())
-			}
+		top := bc
+		bc, path, options := resolveSlashSubcommand(bc, name, data.Options)
+		if bc.Handler == nil {
+			bs.respond(ctx, false, ctx.UserError(gl.MsgUnknownCommand, path))
+			return
 		}
-		argsCombined := strings.Join(args, " ")
-
-		m := bs.US.InteractionToMessageCreate(i, argsCombined)
-		response := bs.US.EmbedToResponse(bc.Handler(argsCombined, m))
-		err := s.InteractionRespond(i.Interaction, response)
-		if err == nil && name == "search" && bs.MS != nil {
-			msg, err := s.InteractionResponse(i.Interaction)
-			if err == nil && msg != nil {
-				var authorID string
-				if i.Member != nil {
-					authorID = i.Member.User.ID
-				} else if i.User != nil {
-					authorID = i.User.ID
-				}
 
-				if authorID != "" {
-					bs.MS.SetSearchMessageID(i.ChannelID, authorID, msg.ID)
+		opts, err := gl.OptionsFromInteraction(bc.SlashOptions, options)
+		if err != nil {
+			bs.respond(ctx, false, bs.US.UserError(ctx.TError(err)))
+			return
+		}
+		ctx.Command, ctx.Options = path, opts
+
+		var deferred bool
+		handler := bs.deferringHandler(i, bc.Slow, &deferred, bc.Handler)
+		cooldown := bc.Cooldown
+		if bc.Modal != nil {
+			if form := bc.Modal(opts); form != nil {
+				// the form replaces the response, and only its submission counts as a use
+				cooldown = gl.Cooldown{}
+				handler = func(ctx *gl.CommandContext) *gl.CommandResult {
+					bs.showModal(i, bs.localizeForm(form, ctx.Locale()))
+					return nil
 				}
 			}
 		}
 
+		r := bs.run(&invocation{
+			ctx:         ctx,
+			name:        name,
+			shown:       "`/" + name + "`",
+			tag:         top.Tag,
+			permissions: top.Permissions | bc.Permissions,
+			cooldown:    cooldown,
+			handler:     handler,
+		})
+
+		msg, err := bs.respond(ctx, deferred, r)
+		if err == nil && r != nil && name == "search" {
+			bs.trackSearchMessage(i, msg)
+		}
+
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		if bs.US.Config.DisableSlashCommands {
+			return
+		}
+
+		bc := bs.getCommand(i.ApplicationCommandData().Name)
+		if bc == nil {
+			return
+		}
+
+		bc, _, options := resolveSlashSubcommand(bc, "", i.ApplicationCommandData().Options)
+		if bc.Autocomplete == nil {
+			return
+		}
+
+		var focused string
+		for _, opt := range options {
+			if opt.Focused && opt.Type == discordgo.ApplicationCommandOptionString {
+				focused = opt.StringValue()
+				break
+			}
+		}
+
+		var userID string
+		if user := gl.InteractionUser(i); user != nil {
+			userID = user.ID
+		}
+
+		choices, ok := []*discordgo.ApplicationCommandOptionChoice{}, true
+		if bc.Cooldown.Period <= 0 || bs.takeCooldown("", autocompleteCooldown, i.GuildID, userID) <= 0 {
+			choices, ok = bc.Autocomplete(focused, i)
+		}
+		if !ok {
+			return
+		}
+
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+			Data: &discordgo.InteractionResponseData{Choices: choices},
+		})
+		if err != nil {
+			bs.logger.Error("could not respond to autocomplete", "error", err)
+		}
+
 	default:
 		bs.logger.Warn("Unhandled interaction type", "type", i.Type)
 		return
@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	runCommandInteraction = "run_command"
+	suggestMinLength      = 3 // shorter words are too close to too many commands
+)
+
+// editDistance returns the optimal string alignment distance between a and b:
+// the insertions, deletions, substitutions and swaps of adjacent letters that
+// turn one into the other.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(ra)][len(rb)]
+}
+
+// closestWord returns the candidate nearest to word, or false when none is
+// close enough to be a typo. Earlier candidates win ties, and one-letter
+// candidates are never suggested.
+func closestWord(word string, candidates []string) (string, bool) {
+	length := utf8.RuneCountInString(word)
+	if length < suggestMinLength {
+		return "", false
+	}
+	maxDistance := max(1, length/3)
+
+	var best string
+	bestDistance := maxDistance + 1
+	for _, c := range candidates {
+		if utf8.RuneCountInString(c) < 2 {
+			continue
+		}
+		if d := editDistance(word, c); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best, bestDistance <= maxDistance
+}
+
+// suggestCommand looks for a command, alias or custom command of the guild
+// that the user may have misspelled as word.
+func (bs *BotService) suggestCommand(word, guildID string) (string, bool) {
+	candidates := make([]string, 0, len(bs.commandNames)+len(bs.aliasMap))
+	for _, name := range bs.commandNames {
+		if bs.US.ModuleEnabled(guildID, bs.handlersMap[name].Tag) {
+			candidates = append(candidates, name)
+		}
+	}
+	// sorted, as earlier candidates win ties
+	for _, alias := range slices.Sorted(maps.Keys(bs.aliasMap)) {
+		if bs.US.ModuleEnabled(guildID, bs.handlersMap[bs.aliasMap[alias]].Tag) {
+			candidates = append(candidates, alias)
+		}
+	}
+	if guildID != "" && bs.US.ModuleEnabled(guildID, gl.TagCustom) {
+		commands, _ := bs.CustomCommands(guildID)
+		for _, c := range commands {
+			candidates = append(candidates, c.Name)
+		}
+	}
+	return closestWord(word, candidates)
+}
+
+// unknownCommand replies to an unknown command, offering to run the closest
+// one with the same arguments.
+func (bs *BotService) unknownCommand(command, args string, ctx *gl.CommandContext) *gl.CommandResult {
+	suggestion, ok := bs.suggestCommand(command, ctx.GuildID)
+	if !ok {
+		return ctx.UserError(gl.MsgUnknownCommand, bs.US.FormatCommand(command))
+	}
+
+	r := ctx.UserError(gl.MsgDidYouMeanFmt, bs.US.FormatCommand(command), bs.US.FormatCommand(suggestion))
+	line := strings.TrimSpace(suggestion + " " + args)
+	if customID := runCommandInteraction + ":" + line; len(customID) <= gl.DiscordCustomIDLimit {
+		r.Message.Components = []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: ctx.T(gl.MsgRunCommandFmt, bs.US.Config.Prefix+suggestion), Style: discordgo.PrimaryButton, CustomID: customID},
+			}},
+		}
+	}
+	return r
+}
+
+// handleRunCommand runs the prefix command line of a "did you mean" button as
+// the user who pressed it.
+func (bs *BotService) handleRunCommand(line string, ctx *gl.CommandContext) *gl.CommandResult {
+	// the command goes through the middlewares again, counted apart from the button
+	run := *ctx
+	_, response, _, err := bs.handleCommand(&run, bs.US.Config.Prefix+line)
+	if err != nil {
+		return ctx.InternalError(errors.New("could not handle command: " + err.Error()))
+	}
+	return response
+}
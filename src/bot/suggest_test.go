@@ -0,0 +1,46 @@
+package bot
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"skip", "skip", 0},
+		{"sikp", "skip", 1},
+		{"plya", "play", 1},
+		{"queu", "queue", 1},
+		{"volme", "volume", 1},
+		{"", "help", 4},
+		{"kitten", "sitting", 3},
+	}
+	for _, tc := range cases {
+		if got := editDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestClosestWord(t *testing.T) {
+	candidates := []string{"play", "playlist", "skip", "shuffle", "s"}
+	cases := []struct {
+		word, want string
+		ok         bool
+	}{
+		{"sikp", "skip", true},
+		{"plai", "play", true},
+		{"shufle", "shuffle", true},
+		{"playlst", "playlist", true},
+		{"xyz", "", false},
+		{"banana", "", false},
+		{"5", "", false},
+		{"sk", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := closestWord(tc.word, candidates)
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Errorf("closestWord(%q) = %q, %v, want %q, %v", tc.word, got, ok, tc.want, tc.ok)
+		}
+	}
+}
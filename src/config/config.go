@@ -17,6 +17,13 @@ type Config struct {
 	Prefix        string
 	Color         int
 	UIAddress     string
+	DataDir       string
+	LocalesDir    string // message catalogs, one <locale>.json per language
+	Locale        string // used when neither the server nor the user chose one
+
+	// Slash command settings
+	SlashCommandsScope string // "global" or "guild"
+	DevGuildID         string // registers commands in this guild only, for instant iteration
 
 	// Music settings
 	ArlCookie        string
@@ -25,6 +32,7 @@ type Config struct {
 	DeezerPassword   string // required for music if ARL_COOKIE is not set
 	AlbumCoverSize   string
 	MaxSearchResults uint64
+	HistoryRetention uint // days of listening history kept, forever if zero
 
 	// Shoot settings
 	MagazineSize    uint
@@ -36,6 +44,7 @@ type Config struct {
 	DisablePrefixCommands bool
 	DisableMusic          bool
 	DisableShoot          bool
+	DisableCooldowns      bool
 }
 
 func requireEnv(key string) string {
@@ -88,6 +97,12 @@ func New() (*Config, error) {
 		Prefix:        getEnv("PREFIX", "$"),
 		Color:         int(color),
 		UIAddress:     getEnv("UI_ADDRESS", ":8080"),
+		DataDir:       getEnv("DATA_DIR", "data"),
+		LocalesDir:    getEnv("LOCALES_DIR", "locales"),
+		Locale:        getEnv("LOCALE", "en-US"),
+
+		SlashCommandsScope: getEnv("SLASH_COMMANDS_SCOPE", "global"),
+		DevGuildID:         getEnv("DEV_GUILD_ID", ""),
 
 		ArlCookie:        getEnv("ARL_COOKIE", ""),
 		SecretKey:        getEnv("SECRET_KEY", ""),
@@ -95,6 +110,7 @@ func New() (*Config, error) {
 		DeezerPassword:   getEnv("DEEZER_PASSWORD", ""),
 		AlbumCoverSize:   getEnv("ALBUM_COVER_SIZE", "xl"),
 		MaxSearchResults: uint64(getEnvUint("MAX_SEARCH_RESULTS", 9)),
+		HistoryRetention: getEnvUint("HISTORY_RETENTION_DAYS", 730),
 
 		MagazineSize:    getEnvUint("MAGAZINE_SIZE", 3),
 		BustProbability: getEnvUint("BUST_PROBABILITY", 50),
@@ -104,6 +120,7 @@ func New() (*Config, error) {
 		DisablePrefixCommands: getEnvBool("DISABLE_PREFIX_COMMANDS", false),
 		DisableMusic:          getEnvBool("DISABLE_MUSIC", false),
 		DisableShoot:          getEnvBool("DISABLE_SHOOT", false),
+		DisableCooldowns:      getEnvBool("DISABLE_COOLDOWNS", false),
 		TimeFormat:            time.RFC3339,
 	}
 
@@ -132,6 +149,18 @@ func (c *Config) Validate() error {
 		return errors.New("UI address must be set")
 	}
 
+	if c.DataDir == "" {
+		return errors.New("data directory must be set")
+	}
+
+	if c.Locale == "" {
+		return errors.New("default locale must be set")
+	}
+
+	if c.SlashCommandsScope != "global" && c.SlashCommandsScope != "guild" {
+		return errors.New("slash commands scope must be one of: global, guild")
+	}
+
 	if !c.DisableMusic {
 		if c.SecretKey == "" {
 			return errors.New("SECRET_KEY must be set if DISABLE_MUSIC is false")
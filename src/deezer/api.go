@@ -0,0 +1,214 @@
+package deezer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/birabittoh/miri"
+)
+
+const apiURL = "https://api.deezer.com"
+
+var (
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+	linkRegex  = regexp.MustCompile(`/(track|album|artist|playlist)/(\d+)`)
+)
+
+type apiError struct {
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// get fetches path from the public Deezer API and decodes the response into v.
+func get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("requesting %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("requesting %s: %s", path, resp.Status)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+
+	var apiErr apiError
+	if json.Unmarshal(raw, &apiErr) == nil && apiErr.Error != nil {
+		return fmt.Errorf("requesting %s: %s", path, apiErr.Error.Message)
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// GetTrack looks up a track by its Deezer ID.
+func GetTrack(ctx context.Context, id string) (*miri.SongResult, error) {
+	var track miri.SongResult
+	if err := get(ctx, "/track/"+url.PathEscape(id), &track); err != nil {
+		return nil, err
+	}
+	return &track, nil
+}
+
+// ParseLink extracts the kind (track, album, artist or playlist) and ID from a
+// Deezer link. Short share links are followed to their destination first.
+func ParseLink(ctx context.Context, link string) (kind, id string, err error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return "", "", errors.New("not a link")
+	}
+
+	host := strings.TrimPrefix(u.Host, "www.")
+	if host == "link.deezer.com" || host == "deezer.page.link" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+		if err != nil {
+			return "", "", err
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return "", "", fmt.Errorf("following share link: %w", err)
+		}
+		resp.Body.Close()
+		u, host = resp.Request.URL, strings.TrimPrefix(resp.Request.URL.Host, "www.")
+	}
+
+	if host != "deezer.com" {
+		return "", "", errors.New("not a deezer link")
+	}
+
+	match := linkRegex.FindStringSubmatch(u.Path)
+	if match == nil {
+		return "", "", errors.New("unsupported deezer link")
+	}
+	return match[1], match[2], nil
+}
+
+// Album, Artist and Playlist are the entries returned by catalog searches.
+type Album struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	NbTracks int    `json:"nb_tracks"`
+	Artist   struct {
+		Name string `json:"name"`
+	} `json:"artist"`
+}
+
+type Artist struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	NbFans int    `json:"nb_fan"`
+}
+
+type Playlist struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	NbTracks int    `json:"nb_tracks"`
+	User     struct {
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+type page[T any] struct {
+	Data []T `json:"data"`
+}
+
+func search[T any](ctx context.Context, kind, query string, limit int) ([]T, error) {
+	var p page[T]
+	err := get(ctx, fmt.Sprintf("/search/%s?q=%s&limit=%d", kind, url.QueryEscape(query), limit), &p)
+	return p.Data, err
+}
+
+// SearchAlbums, SearchArtists and SearchPlaylists look up to limit catalog entries matching query.
+func SearchAlbums(ctx context.Context, query string, limit int) ([]Album, error) {
+	return search[Album](ctx, "album", query, limit)
+}
+
+func SearchArtists(ctx context.Context, query string, limit int) ([]Artist, error) {
+	return search[Artist](ctx, "artist", query, limit)
+}
+
+func SearchPlaylists(ctx context.Context, query string, limit int) ([]Playlist, error) {
+	return search[Playlist](ctx, "playlist", query, limit)
+}
+
+// AlbumTracks returns the title and tracklist of an album. The tracks listed
+// in an album do not repeat it, so it is copied into each of them.
+func AlbumTracks(ctx context.Context, id string) (string, []miri.SongResult, error) {
+	var raw json.RawMessage
+	if err := get(ctx, "/album/"+url.PathEscape(id), &raw); err != nil {
+		return "", nil, err
+	}
+
+	var album struct {
+		Title  string                `json:"title"`
+		Tracks page[miri.SongResult] `json:"tracks"`
+	}
+	if err := json.Unmarshal(raw, &album); err != nil {
+		return "", nil, err
+	}
+
+	tracks := album.Tracks.Data
+	for i := range tracks {
+		if err := json.Unmarshal(raw, &tracks[i].Album); err != nil {
+			return "", nil, err
+		}
+	}
+	return album.Title, tracks, nil
+}
+
+// ArtistTopTracks returns the name and up to limit most popular tracks of an artist.
+func ArtistTopTracks(ctx context.Context, id string, limit int) (string, []miri.SongResult, error) {
+	var artist Artist
+	if err := get(ctx, "/artist/"+url.PathEscape(id), &artist); err != nil {
+		return "", nil, err
+	}
+
+	var top page[miri.SongResult]
+	err := get(ctx, fmt.Sprintf("/artist/%s/top?limit=%d", url.PathEscape(id), limit), &top)
+	return artist.Name, top.Data, err
+}
+
+// PlaylistTracks returns the title and up to limit tracks of a public playlist.
+func PlaylistTracks(ctx context.Context, id string, limit int) (string, []miri.SongResult, error) {
+	var playlist Playlist
+	if err := get(ctx, "/playlist/"+url.PathEscape(id), &playlist); err != nil {
+		return "", nil, err
+	}
+
+	var tracks page[miri.SongResult]
+	err := get(ctx, fmt.Sprintf("/playlist/%s/tracks?limit=%d", url.PathEscape(id), limit), &tracks)
+	return playlist.Title, tracks.Data, err
+}
+
+// TrackDetails is the metadata of a track that searches leave out.
+type TrackDetails struct {
+	Link        string  `json:"link"`
+	ReleaseDate string  `json:"release_date"`
+	Explicit    bool    `json:"explicit_lyrics"`
+	BPM         float64 `json:"bpm"`
+}
+
+// GetTrackDetails looks up the full metadata of a track by its Deezer ID.
+func GetTrackDetails(ctx context.Context, id string) (*TrackDetails, error) {
+	var details TrackDetails
+	if err := get(ctx, "/track/"+url.PathEscape(id), &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
@@ -0,0 +1,36 @@
+package globals
+
+import (
+	"slices"
+	"strings"
+)
+
+// HasSubcommands reports whether the command is a group of subcommands.
+func (bc BotCommand) HasSubcommands() bool {
+	return len(bc.Subcommands) > 0
+}
+
+// Subcommand looks up a direct subcommand by name or short code.
+func (bc BotCommand) Subcommand(name string) (string, *BotCommand) {
+	name = strings.ToLower(name)
+	if sub, ok := bc.Subcommands[name]; ok {
+		return name, &sub
+	}
+
+	for subName, sub := range bc.Subcommands {
+		if sub.ShortCode != "" && sub.ShortCode == name {
+			return subName, &sub
+		}
+	}
+	return "", nil
+}
+
+// SubcommandNames returns the names of the direct subcommands, sorted.
+func (bc BotCommand) SubcommandNames() []string {
+	names := make([]string, 0, len(bc.Subcommands))
+	for name := range bc.Subcommands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
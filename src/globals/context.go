@@ -0,0 +1,128 @@
+package globals
+
+import (
+	"errors"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Surface is where a command was invoked from.
+type Surface string
+
+const (
+	SurfacePrefix      Surface = "prefix"  // a message starting with the prefix
+	SurfaceSlash       Surface = "slash"   // a slash command
+	SurfaceContextMenu Surface = "context" // a user or message context menu entry
+	SurfaceComponent   Surface = "button"  // a button, select menu or form, e.g. "did you mean"
+)
+
+// CommandContext is a single invocation of a command, or a use of one of the
+// components the bot posted, whichever surface it came from.
+type CommandContext struct {
+	GuildID     string // empty in direct messages
+	ChannelID   string
+	Member      *discordgo.Member // nil in direct messages
+	Author      *discordgo.User
+	Attachments []*discordgo.MessageAttachment // sent with the message or through attachment options
+	Message     *discordgo.Message             // the invoking message, nil for interactions
+	Interaction *discordgo.InteractionCreate   // nil for prefix commands
+	Surface     Surface
+	Command     string // path of the command, e.g. "playlist add"
+	Options     CommandOptions
+
+	clientLocale string // language of the user's Discord client, only sent with interactions
+	us           *UtilsService
+}
+
+// MessageContext returns the context of a prefix command sent in m.
+func (us *UtilsService) MessageContext(m *discordgo.MessageCreate) *CommandContext {
+	return &CommandContext{
+		GuildID:     m.GuildID,
+		ChannelID:   m.ChannelID,
+		Member:      m.Member,
+		Author:      m.Author,
+		Attachments: m.Attachments,
+		Message:     m.Message,
+		Surface:     SurfacePrefix,
+		Options:     CommandOptions{},
+		us:          us,
+	}
+}
+
+// InteractionContext returns the context of i, which came from surface.
+func (us *UtilsService) InteractionContext(surface Surface, i *discordgo.InteractionCreate) *CommandContext {
+	c := &CommandContext{
+		GuildID:      i.GuildID,
+		ChannelID:    i.ChannelID,
+		Member:       i.Member,
+		Author:       InteractionUser(i),
+		Interaction:  i,
+		Surface:      surface,
+		Options:      CommandOptions{},
+		clientLocale: string(i.Locale),
+		us:           us,
+	}
+
+	// files uploaded through attachment options, so handlers find them like on messages
+	if i.Type == discordgo.InteractionApplicationCommand {
+		if resolved := i.ApplicationCommandData().Resolved; resolved != nil {
+			for _, a := range resolved.Attachments {
+				c.Attachments = append(c.Attachments, a)
+			}
+		}
+	}
+	return c
+}
+
+// Locale returns the language replies should be written in: the one chosen
+// for the guild, then the one of the user's Discord client, then the default one.
+func (c *CommandContext) Locale() string {
+	if c.GuildID != "" {
+		if locale := c.us.GuildSettings(c.GuildID).Locale; locale != "" {
+			return locale
+		}
+	}
+	if c.clientLocale != "" {
+		return c.clientLocale
+	}
+	return c.us.Config.Locale
+}
+
+// T translates the message key for the invoking user.
+func (c *CommandContext) T(key string, args ...any) string {
+	return c.us.Translator.Sprintf(c.Locale(), key, args...)
+}
+
+// TError translates err if it carries a message key, as the ones returned
+// while parsing options do.
+func (c *CommandContext) TError(err error) string {
+	var msgErr *MessageError
+	if errors.As(err, &msgErr) {
+		return c.T(msgErr.Key, msgErr.Args...)
+	}
+	return err.Error()
+}
+
+// Reply, UserError and InternalError are the CommandResult helpers of
+// UtilsService, with the message key translated for the invoking user.
+func (c *CommandContext) Reply(key string, args ...any) *CommandResult {
+	return c.us.Reply(c.T(key, args...))
+}
+
+func (c *CommandContext) UserError(key string, args ...any) *CommandResult {
+	return c.us.UserError(c.T(key, args...))
+}
+
+func (c *CommandContext) InternalError(err error) *CommandResult {
+	r := c.us.InternalError(err)
+	c.LocalizeResult(r)
+	return r
+}
+
+// LocalizeResult words the generic message of an internal error for the
+// invoking user, as it is built before their language is known.
+func (c *CommandContext) LocalizeResult(r *CommandResult) {
+	if r.Kind == ResultInternalError && len(r.Message.Embeds) > 0 {
+		r.Message.Embeds[0].Description = c.T(MsgError)
+	}
+}
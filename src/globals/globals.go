@@ -2,6 +2,7 @@ package globals
 
 import (
 	"os"
+	"slices"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -17,51 +18,177 @@ const (
 	MsgSameVoiceChannel = "You need to be in the same voice channel to use this command."
 	MsgNoVoiceChannel   = "You need to be in a voice channel to use this command."
 	MsgUnknownCommand   = "Unknown command: %s."
+	MsgDidYouMeanFmt    = "Unknown command %s — did you mean %s?"
+	MsgRunCommandFmt    = "▶ Run %s"
+	MsgUsageSubcommand  = "Usage: %s <%s>."
+	MsgMissingOptionFmt = "Missing required argument `%s`."
+	MsgInvalidOptionFmt = "Invalid value for `%s`."
+	MsgOptionRangeFmt   = "`%s` must be between %g and %g."
+	MsgInvalidMention   = "Please, mention a valid user, role or channel."
 	MsgPrefixSet        = "Prefix set to `%s`."
 	MsgPrefixTooLong    = "Prefix is too long."
 	MsgUsagePrefix      = "Usage: %s <new prefix>."
-	MsgHelp             = "**Bot commands:**\n"
 	MsgHelpFmt          = "%s - _%s_"
+	MsgHelpFooterFmt    = "Use %s to learn more about a command."
+	MsgHelpCategory     = "Choose a category"
+	MsgHelpUsage        = "Usage"
+	MsgHelpArguments    = "Arguments"
+	MsgHelpAliases      = "Aliases"
+	MsgHelpExamples     = "Examples"
+	MsgHelpPermissions  = "Required permissions"
+	MsgHelpSubcommands  = "Subcommands"
+	MsgHelpOptional     = " _(optional)_"
+	MsgHelpGeneral      = "⚙️ General"
+	MsgHelpMusic        = "🎵 Music"
+	MsgHelpShoot        = "🔫 Shoot"
+	MsgModuleDisabled   = "This command is disabled in this server."
+	MsgMissingPerms     = "You do not have permission to use this command."
+	MsgCooldownFmt      = "Slow down! You can use %s again in %s."
+	MsgModuleToggledFmt = "Module `%s` is now %s."
+	MsgLanguageSetFmt   = "I will now answer in `%s`."
+	MsgEnabled          = "enabled"
+	MsgDisabled         = "disabled"
 	MsgOrderedList      = "%d. %s\n"
 	MsgUnorderedList    = "* %s\n"
 
+	// Custom command messages
+	MsgCustomSavedFmt          = "Saved custom command %s."
+	MsgCustomDeletedFmt        = "Deleted custom command %s."
+	MsgCustomNotFoundFmt       = "Could not find custom command `%s`."
+	MsgCustomReservedFmt       = "`%s` is already a bot command."
+	MsgCustomNameInvalidFmt    = "Names must be a single word of at most %d characters."
+	MsgCustomResponseLengthFmt = "Responses must be between 1 and %d characters."
+	MsgCustomLimitFmt          = "A server can have at most %d custom commands."
+	MsgCustomLineFmt           = "%s - %s"
+	MsgNoCustomCommands        = "There are no custom commands in this server."
+	MsgAutoResponseSavedFmt    = "I will now answer to `%s`."
+	MsgAutoResponseDeletedFmt  = "I will no longer answer to `%s`."
+	MsgAutoResponseNotFoundFmt = "There is no auto-response to `%s`."
+	MsgAutoResponseLimitFmt    = "A server can have at most %d auto-responses."
+	MsgNoAutoResponses         = "There are no auto-responses in this server."
+
 	// Shoot messages
 	MsgCantKickUser    = "Could not kick user from the voice channel."
 	MsgOutOfBullets    = "💨 Too bad... You're out of bullets."
 	MsgNoOtherUsersFmt = "There is no one else to shoot in <#%s>."
 	MsgMagazineFmt     = "_%d/%d bullets left in your magazine._"
 	MsgShootFmt        = "💥 *Bang!* <@%s> was shot. %s"
+	MsgTargetNotHere   = "Your target is not in your voice channel."
 
 	// Music messages
-	MsgCanceled           = "Canceled."
-	MsgPaused             = "Paused."
-	MsgResumed            = "Resumed."
-	MsgSkipped            = "Skipped."
-	MsgCleared            = "Cleared."
-	MsgSeeked             = "Seeked to %s."
-	MsgLeft               = "Left."
-	MsgNoLyrics           = "No lyrics found for this song."
-	MsgInvalidTrackNumber = "Invalid track selection."
-	MsgCantFindSearch     = "Could not find your previous search, please try again."
-	MsgInvalidSeekTime    = "Please provide a valid seek time (e.g., 1m30s or 3m)."
-
-	DiscordEmbedDescriptionLimit   = 4096
-	DefaultSearchOptionName        = "input"
-	DefaultSearchOptionDescription = "command arguments"
-	DiscordPermissions             = 17825792
+	MsgCancel              = "Cancel"
+	MsgCanceled            = "Canceled."
+	MsgPaused              = "Paused."
+	MsgResumed             = "Resumed."
+	MsgSkipped             = "Skipped."
+	MsgNoPrevious          = "There is no previous song."
+	MsgPreviousFmt         = "Going back to %s."
+	MsgReplaying           = "Replaying the current song."
+	MsgCleared             = "Cleared."
+	MsgSeeked              = "Seeked to %s."
+	MsgLeft                = "Left."
+	MsgNowPlaying          = "Now playing"
+	MsgButtonPause         = "⏸ Pause"
+	MsgButtonResume        = "▶ Resume"
+	MsgButtonSkip          = "⏭ Skip"
+	MsgButtonLoop          = "🔁 Loop"
+	MsgButtonShuffle       = "🔀 Shuffle"
+	MsgButtonStop          = "⏹ Stop"
+	MsgFieldDuration       = "Duration"
+	MsgFieldReleased       = "Released"
+	MsgFieldBPM            = "BPM"
+	MsgFieldExplicit       = "Explicit"
+	MsgFieldRequester      = "Requested by"
+	MsgFieldPosition       = "Position"
+	MsgPositionFmt         = "#%d, plays in %s"
+	MsgETAFmt              = " · in %s"
+	MsgYes                 = "Yes"
+	MsgUpNextFmt           = "%d up next"
+	MsgLoopOn              = "Looping"
+	MsgNoLyrics            = "No lyrics found for this song."
+	MsgNoSyncedLyrics      = "There are no synced lyrics for this song."
+	MsgLyricsLive          = "🔴 Live lyrics"
+	MsgPageFmt             = "Page %d/%d"
+	MsgPlayThis            = "▶ Play this"
+	MsgListeningFmt        = "<@%s> is listening to:"
+	MsgNotListeningFmt     = "<@%s> is not listening to anything."
+	MsgInvalidTrackNumber  = "Invalid track selection."
+	MsgCantFindSearch      = "Could not find your previous search, please try again."
+	MsgInvalidSeekTime     = "Please provide a valid seek time (e.g., 1m30s or 3m)."
+	MsgRemovedFmt          = "Removed %s."
+	MsgMovedFmt            = "Moved %s to position %d."
+	MsgShuffled            = "Shuffled."
+	MsgVolumeFmt           = "Volume is set to %d%%."
+	MsgAddedTracksFmt      = "Added %d songs to the queue."
+	MsgChooseTracks        = "Choose one or more songs"
+	MsgChooseTrack         = "Choose a song to add"
+	MsgBrowsePick          = "Choose one to see its songs"
+	MsgCatalogLineFmt      = "**%s** - %s"
+	MsgAlbumDetailFmt      = "%s, %d songs"
+	MsgArtistDetailFmt     = "%d fans"
+	MsgMoreTracksFmt       = "_…and %d more_\n"
+	MsgAddAllFmt           = "➕ Add all %d"
+	MsgAddedFromFmt        = "Added %d songs from **%s** to the queue."
+	MsgSearchTitle         = "Search"
+	MsgPlaylistTitle       = "Save playlist"
+	MsgPlaylistSavedFmt    = "Saved %d songs as playlist `%s`."
+	MsgPlaylistEmpty       = "This playlist is empty."
+	MsgNothingToSave       = "There is nothing to save."
+	MsgPlaylistNameLength  = "Playlist names must be at most 100 characters."
+	MsgPlaylistNotFoundFmt = "Could not find playlist `%s`."
+	MsgPlaylistLoadedFmt   = "Added %d songs from playlist `%s`."
+	MsgPlaylistMissingFmt  = "%d songs are no longer available."
+	MsgPlaylistAddedFmt    = "Added %s to playlist `%s`."
+	MsgPlaylistRemovedFmt  = "Removed song %d from playlist `%s`."
+	MsgPlaylistDeletedFmt  = "Deleted playlist `%s`."
+	MsgPlaylistExistsFmt   = "Playlist `%s` already exists."
+	MsgPlaylistReplace     = "Replace it"
+	MsgPlaylistLineFmt     = "**%s** - %d songs (%s)"
+	MsgPlaylistHeaderFmt   = "**%s** - %d songs\n"
+	MsgNoPlaylists         = "There are no saved playlists."
+	MsgTrackUnavailable    = "_unavailable_"
+	MsgTrackGone           = "This track is no longer available."
+	MsgExportedFmt         = "Exported %d songs from `%s`."
+	MsgImportNoFile        = "Please, attach a playlist file (M3U8, XSPF or JSON)."
+	MsgImportUnreadable    = "Could not read this playlist file."
+	MsgImportTooLarge      = "This playlist file is too large."
+	MsgImportSummaryFmt    = "**%s**: %d matched, %d ambiguous, %d not found.\n"
+	MsgImportMatchedFmt    = "✅ %s"
+	MsgImportAmbiguousFmt  = "❓ %s → %s"
+	MsgImportNotFoundFmt   = "❌ %s"
+
+	// History and stats messages
+	MsgNoHistory      = "Nothing was played in this period."
+	MsgHistoryLineFmt = "<t:%d:R> %s - **%s**"
+	MsgRequestedByFmt = " · <@%s>"
+	MsgHistorySkipped = " · _skipped_"
+	MsgTopLineFmt     = "%s - %d plays"
+
+	DiscordEmbedDescriptionLimit = 4096
+	DiscordMaxChoices            = 25
+	DiscordChoiceLimit           = 100
+	DiscordCustomIDLimit         = 100
+	DiscordPermissions           = 17825792
 
 	LoggerMain  = "main "
 	LoggerMusic = "music"
 	LoggerShoot = "shoot"
 	LoggerUI    = "ui   "
+	LoggerUtils = "utils"
 
 	AudioChannels    int    = 2
 	AudioFrameRate   int    = 48000
 	AudioFrameSize   int    = 960
 	AudioBitrate     int    = 128
 	AudioApplication string = "voip"
+	AudioVolume      int    = 100
+	AudioMaxVolume   int    = 200
 	MaxBytes         int    = (AudioFrameSize * AudioChannels) * 2
 
+	// AutocompleteDebounce is how long a user must stop typing before an
+	// autocomplete request actually hits the search API.
+	AutocompleteDebounce = 300 * time.Millisecond
+
 	// GatewayHealthThreshold is how long the gateway can go without a heartbeat
 	// ACK before /healthz reports the bot as unhealthy (~2 missed heartbeats).
 	GatewayHealthThreshold = 90 * time.Second
@@ -76,22 +203,100 @@ const (
 var CommitID string
 
 type SlashOption struct {
-	Name        string
-	Description string
-	Type        discordgo.ApplicationCommandOptionType
-	Required    bool
+	Name         string
+	Description  string
+	Type         discordgo.ApplicationCommandOptionType
+	Required     bool
+	Autocomplete bool
+	Choices      []*discordgo.ApplicationCommandOptionChoice
+	ChannelTypes []discordgo.ChannelType
+	MinValue     *float64
+	MaxValue     float64
+	Duration     bool // string option holding a duration such as 1m30s
+	Flag         bool // only set as --name value in prefix commands, or bare --name for booleans
+}
+
+// Modules that can be toggled per guild, matching BotCommand.Tag.
+const (
+	TagGeneral = "general"
+	TagMusic   = "music"
+	TagShoot   = "shoot"
+	TagCustom  = "custom" // the custom commands written by the admins of the guild
+)
+
+// GuildSettings are the per-guild preferences saved in the store.
+type GuildSettings struct {
+	DisabledModules []string `json:"disabled_modules,omitempty"`
+	Locale          string   `json:"locale,omitempty"` // Discord locale of the replies, e.g. "it"
+}
+
+// ModuleEnabled reports whether commands tagged with tag can be used in the guild.
+func (gs GuildSettings) ModuleEnabled(tag string) bool {
+	return tag == TagGeneral || !slices.Contains(gs.DisabledModules, tag)
+}
+
+// ResultKind tells apart successful replies from the two kinds of failure.
+type ResultKind int
+
+const (
+	ResultOK            ResultKind = iota
+	ResultUserError                // the user asked for something that cannot be done
+	ResultInternalError            // something broke on our side
+)
+
+// CommandResult is a handler's reply; each surface renders it in its own way.
+type CommandResult struct {
+	Message   *discordgo.MessageSend
+	Kind      ResultKind
+	Ephemeral bool // only shown to the invoking user, where the surface allows it
+	// Sent, when set, is called with the posted reply, e.g. to keep editing it.
+	Sent func(*discordgo.Message)
+}
+
+// Cooldown limits a command to Uses runs in each Period, counted for every
+// user or, with PerGuild, for the whole server.
+type Cooldown struct {
+	Uses     int
+	Period   time.Duration
+	PerGuild bool
+	Group    string // commands of the same group share their runs, each command has its own if empty
 }
 
 type BotCommand struct {
-	Handler      func(string, *discordgo.MessageCreate) *discordgo.MessageSend
+	Handler      func(*CommandContext) *CommandResult
+	Autocomplete func(string, *discordgo.InteractionCreate) ([]*discordgo.ApplicationCommandOptionChoice, bool)
 	ShortCode    string
 	Alias        string
 	Help         string
+	Examples     []string // arguments shown by help <command>, e.g. "1m30s"
 	SlashOptions []SlashOption
+	Permissions  int64                 // required member permissions, e.g. discordgo.PermissionManageGuild
+	Subcommands  map[string]BotCommand // subcommands, or groups when they have their own
+	Slow         bool                  // may take longer than Discord's 3s deadline, so the response is deferred
+	Cooldown     Cooldown              // how often it can be used, without limits if zero
 	Tag          string
+
+	// Modal is asked before a slash command runs; a non-nil form is shown to
+	// collect the missing options instead of running the handler.
+	Modal func(CommandOptions) *discordgo.InteractionResponseData
+}
+
+// ContextCommand is a command in the user or message context menu. Its handler
+// receives the target user ID or message content as the option named Option.
+type ContextCommand struct {
+	Type     discordgo.ApplicationCommandType
+	Handler  func(*CommandContext) *CommandResult
+	Option   string
+	Slow     bool
+	Cooldown Cooldown
+	Tag      string
 }
 
+// BotInteraction handles the buttons, select menus and forms whose custom ID
+// starts with its name; the handler receives the rest of the ID.
 type BotInteraction struct {
-	Handler func(string, *discordgo.InteractionCreate) *discordgo.MessageSend
-	Tag     string
+	Handler  func(string, *CommandContext) *CommandResult
+	Slow     bool
+	Cooldown Cooldown
+	Tag      string
 }
@@ -0,0 +1,87 @@
+package globals
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ModalForm builds a modal with one text input per option. The option
+// description is used as label, so it must fit in 45 characters.
+func ModalForm(customID, title string, defs []SlashOption) *discordgo.InteractionResponseData {
+	rows := make([]discordgo.MessageComponent, 0, len(defs))
+	for _, def := range defs {
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.TextInput{
+				CustomID:    def.Name,
+				Label:       def.Description,
+				Style:       discordgo.TextInputShort,
+				Placeholder: def.placeholder(),
+				Required:    def.Required,
+				MaxLength:   DiscordChoiceLimit,
+			},
+		}})
+	}
+
+	return &discordgo.InteractionResponseData{
+		CustomID:   customID,
+		Title:      title,
+		Components: rows,
+	}
+}
+
+// placeholder hints at the values accepted by a modal field.
+func (opt SlashOption) placeholder() string {
+	switch {
+	case len(opt.Choices) > 0:
+		values := make([]string, 0, len(opt.Choices))
+		for _, c := range opt.Choices {
+			values = append(values, fmt.Sprint(c.Value))
+		}
+		return strings.Join(values, " / ")
+	case opt.Type == discordgo.ApplicationCommandOptionBoolean:
+		return "yes / no"
+	case opt.Type == discordgo.ApplicationCommandOptionInteger && opt.MaxValue != 0:
+		return fmt.Sprintf("%g-%g", opt.minValue(), opt.MaxValue)
+	case opt.Duration:
+		return "1m30s"
+	}
+	return ""
+}
+
+// OptionsFromModal reads the text inputs of a submitted modal built by ModalForm,
+// validating them against defs like the arguments of a prefix command.
+func OptionsFromModal(defs []SlashOption, data discordgo.ModalSubmitInteractionData) (CommandOptions, error) {
+	values := map[string]string{}
+	for _, c := range data.Components {
+		row, ok := c.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, rc := range row.Components {
+			if input, ok := rc.(*discordgo.TextInput); ok {
+				values[input.CustomID] = strings.TrimSpace(input.Value)
+			}
+		}
+	}
+
+	opts := CommandOptions{}
+	for _, def := range defs {
+		raw := values[def.Name]
+		if raw == "" {
+			if def.Required {
+				return nil, Errorf(MsgMissingOptionFmt, def.Name)
+			}
+			continue
+		}
+
+		v, err := def.parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		opts[def.Name] = v
+	}
+
+	return opts, nil
+}
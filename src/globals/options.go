@@ -0,0 +1,291 @@
+package globals
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// CommandOptions holds the parsed options of a command invocation, keyed by
+// option name. Values are string, int64, bool or time.Duration; user, role and
+// channel options are stored as their IDs.
+type CommandOptions map[string]any
+
+func (o CommandOptions) Has(name string) bool {
+	_, ok := o[name]
+	return ok
+}
+
+func (o CommandOptions) String(name string) string {
+	v, _ := o[name].(string)
+	return v
+}
+
+func (o CommandOptions) Int(name string) (int64, bool) {
+	v, ok := o[name].(int64)
+	return v, ok
+}
+
+func (o CommandOptions) Bool(name string) bool {
+	v, _ := o[name].(bool)
+	return v
+}
+
+func (o CommandOptions) Duration(name string) (time.Duration, bool) {
+	v, ok := o[name].(time.Duration)
+	return v, ok
+}
+
+// User returns the ID of the user passed as option name.
+func (o CommandOptions) User(name string) string {
+	return o.String(name)
+}
+
+// Channel returns the ID of the channel passed as option name.
+func (o CommandOptions) Channel(name string) string {
+	return o.String(name)
+}
+
+// MessageError is an error worded by a message key, so it can be shown to
+// users in their language.
+type MessageError struct {
+	Key  string
+	Args []any
+}
+
+// Errorf returns a MessageError for key, formatted with args.
+func Errorf(key string, args ...any) error {
+	return &MessageError{Key: key, Args: args}
+}
+
+func (e *MessageError) Error() string {
+	if len(e.Args) == 0 {
+		return e.Key
+	}
+	return fmt.Sprintf(e.Key, e.Args...)
+}
+
+// ApplicationCommandOption converts the option to its Discord representation.
+func (opt SlashOption) ApplicationCommandOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:         opt.Type,
+		Name:         opt.Name,
+		Description:  opt.Description,
+		Required:     opt.Required,
+		Autocomplete: opt.Autocomplete,
+		Choices:      opt.Choices,
+		ChannelTypes: opt.ChannelTypes,
+		MinValue:     opt.MinValue,
+		MaxValue:     opt.MaxValue,
+	}
+}
+
+// parse converts the raw text of an option to its typed value.
+func (opt SlashOption) parse(raw string) (any, error) {
+	invalid := Errorf(MsgInvalidOptionFmt, opt.Name)
+
+	if len(opt.Choices) > 0 {
+		for _, c := range opt.Choices {
+			if strings.EqualFold(raw, fmt.Sprint(c.Value)) || strings.EqualFold(raw, c.Name) {
+				raw = fmt.Sprint(c.Value)
+				break
+			}
+		}
+	}
+
+	switch opt.Type {
+	case discordgo.ApplicationCommandOptionInteger:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		if (opt.MinValue != nil && float64(v) < *opt.MinValue) || (opt.MaxValue != 0 && float64(v) > opt.MaxValue) {
+			return nil, Errorf(MsgOptionRangeFmt, opt.Name, opt.minValue(), opt.MaxValue)
+		}
+		return v, nil
+	case discordgo.ApplicationCommandOptionBoolean:
+		switch strings.ToLower(raw) {
+		case "yes", "y", "on":
+			return true, nil
+		case "no", "n", "off":
+			return false, nil
+		}
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, invalid
+		}
+		return v, nil
+	case discordgo.ApplicationCommandOptionUser:
+		return parseMention(raw, "<@", "<@!")
+	case discordgo.ApplicationCommandOptionRole:
+		return parseMention(raw, "<@&")
+	case discordgo.ApplicationCommandOptionChannel:
+		return parseMention(raw, "<#")
+	}
+
+	if len(opt.Choices) > 0 && !opt.hasChoice(raw) {
+		return nil, invalid
+	}
+	if opt.Duration {
+		v, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, invalid
+		}
+		return v, nil
+	}
+	return raw, nil
+}
+
+func (opt SlashOption) minValue() float64 {
+	if opt.MinValue == nil {
+		return 0
+	}
+	return *opt.MinValue
+}
+
+func (opt SlashOption) hasChoice(raw string) bool {
+	for _, c := range opt.Choices {
+		if fmt.Sprint(c.Value) == raw {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMention extracts a snowflake from a mention with one of the given prefixes, or a raw ID.
+func parseMention(raw string, prefixes ...string) (string, error) {
+	id := raw
+	for _, p := range prefixes {
+		if after, ok := strings.CutPrefix(raw, p); ok {
+			id = strings.TrimSuffix(after, ">")
+		}
+	}
+
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return "", Errorf(MsgInvalidMention)
+	}
+	return id, nil
+}
+
+// ParseOptions maps the arguments of a prefix command onto its declared
+// options, in order. Each option takes one word, except for a trailing string
+// option which takes the rest of the message. Flag options are taken out of
+// the arguments first, wherever they appear.
+func ParseOptions(defs []SlashOption, args string) (CommandOptions, error) {
+	opts := CommandOptions{}
+	words, err := parseFlags(defs, strings.Fields(args), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	defs = slices.DeleteFunc(slices.Clone(defs), func(def SlashOption) bool { return def.Flag })
+	for i, def := range defs {
+		if def.Type == discordgo.ApplicationCommandOptionAttachment {
+			continue // files come with the message itself
+		}
+		if len(words) == 0 {
+			if def.Required {
+				return nil, Errorf(MsgMissingOptionFmt, def.Name)
+			}
+			continue
+		}
+
+		raw := words[0]
+		words = words[1:]
+		if i == len(defs)-1 && def.Type == discordgo.ApplicationCommandOptionString && len(words) > 0 {
+			raw = strings.Join(append([]string{raw}, words...), " ")
+			words = nil
+		}
+
+		v, err := def.parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		opts[def.Name] = v
+	}
+
+	return opts, nil
+}
+
+// parseFlags stores the flag options found in words, written as --name value
+// or --name=value, and returns the remaining words. Boolean flags are set by a
+// bare --name and only take a value as --name=value.
+func parseFlags(defs []SlashOption, words []string, opts CommandOptions) ([]string, error) {
+	rest := make([]string, 0, len(words))
+	for i := 0; i < len(words); i++ {
+		name, ok := strings.CutPrefix(words[i], "--")
+		name, raw, hasValue := strings.Cut(name, "=")
+		n := slices.IndexFunc(defs, func(def SlashOption) bool { return def.Flag && def.Name == name })
+		if !ok || n < 0 {
+			rest = append(rest, words[i])
+			continue
+		}
+
+		switch {
+		case hasValue: // --name=value
+		case defs[n].Type == discordgo.ApplicationCommandOptionBoolean:
+			raw = "true"
+		case i+1 == len(words):
+			return nil, Errorf(MsgMissingOptionFmt, name)
+		default:
+			i++
+			raw = words[i]
+		}
+
+		v, err := defs[n].parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		opts[name] = v
+	}
+
+	for _, def := range defs {
+		if def.Flag && def.Required && !opts.Has(def.Name) {
+			return nil, Errorf(MsgMissingOptionFmt, def.Name)
+		}
+	}
+	return rest, nil
+}
+
+// OptionsFromInteraction reads the options of a slash command, validating them against defs.
+func OptionsFromInteraction(defs []SlashOption, data []*discordgo.ApplicationCommandInteractionDataOption) (CommandOptions, error) {
+	opts := CommandOptions{}
+	for _, def := range defs {
+		var value *discordgo.ApplicationCommandInteractionDataOption
+		for _, o := range data {
+			if o.Name == def.Name {
+				value = o
+				break
+			}
+		}
+
+		if value == nil {
+			if def.Required {
+				return nil, Errorf(MsgMissingOptionFmt, def.Name)
+			}
+			continue
+		}
+
+		switch value.Type {
+		case discordgo.ApplicationCommandOptionString:
+			v, err := def.parse(value.StringValue())
+			if err != nil {
+				return nil, err
+			}
+			opts[def.Name] = v
+		case discordgo.ApplicationCommandOptionInteger:
+			opts[def.Name] = value.IntValue()
+		case discordgo.ApplicationCommandOptionBoolean:
+			opts[def.Name] = value.BoolValue()
+		default:
+			// users, roles and channels all carry their snowflake as value
+			opts[def.Name] = fmt.Sprint(value.Value)
+		}
+	}
+
+	return opts, nil
+}
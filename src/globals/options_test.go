@@ -0,0 +1,128 @@
+package globals
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseOptions(t *testing.T) {
+	minIndex := 1.0
+	defs := []SlashOption{
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "index", Required: true, MinValue: &minIndex, MaxValue: 10},
+		{Type: discordgo.ApplicationCommandOptionUser, Name: "target"},
+		{Type: discordgo.ApplicationCommandOptionString, Name: "query"},
+	}
+
+	cases := []struct {
+		name    string
+		args    string
+		wantErr bool
+		check   func(CommandOptions) bool
+	}{
+		{"trailing string takes the rest", "3 <@!1234> never gonna give", false, func(o CommandOptions) bool {
+			i, _ := o.Int("index")
+			return i == 3 && o.User("target") == "1234" && o.String("query") == "never gonna give"
+		}},
+		{"optional options may be omitted", "2", false, func(o CommandOptions) bool {
+			return !o.Has("target") && !o.Has("query")
+		}},
+		{"missing required", "", true, nil},
+		{"not an integer", "two", true, nil},
+		{"out of range", "11", true, nil},
+		{"bad mention", "1 @someone", true, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := ParseOptions(defs, tc.args)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseOptions(%q) error = %v, wantErr %v", tc.args, err, tc.wantErr)
+			}
+			if tc.check != nil && !tc.check(opts) {
+				t.Errorf("ParseOptions(%q) = %v", tc.args, opts)
+			}
+		})
+	}
+}
+
+func TestParseOptionsFlags(t *testing.T) {
+	minIndex := 1.0
+	defs := []SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "query", Required: true},
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "position", MinValue: &minIndex, Flag: true},
+	}
+
+	cases := []struct {
+		args     string
+		query    string
+		position int64
+		wantErr  bool
+	}{
+		{"never gonna give", "never gonna give", 0, false},
+		{"--position 2 never gonna give", "never gonna give", 2, false},
+		{"never gonna --position=3 give", "never gonna give", 3, false},
+		{"never --unknown 4", "never --unknown 4", 0, false},
+		{"never gonna --position", "", 0, true},
+		{"--position 0 never", "", 0, true},
+	}
+
+	for _, tc := range cases {
+		opts, err := ParseOptions(defs, tc.args)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("ParseOptions(%q) error = %v, wantErr %v", tc.args, err, tc.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		position, _ := opts.Int("position")
+		if opts.String("query") != tc.query || position != tc.position {
+			t.Errorf("ParseOptions(%q) = %v", tc.args, opts)
+		}
+	}
+}
+
+func TestParseOptionsBoolFlag(t *testing.T) {
+	defs := []SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "query"},
+		{Type: discordgo.ApplicationCommandOptionBoolean, Name: "live", Flag: true},
+	}
+
+	cases := []struct {
+		args  string
+		query string
+		live  bool
+	}{
+		{"never gonna", "never gonna", false},
+		{"--live", "", true},
+		{"never --live gonna", "never gonna", true},
+		{"--live=no never", "never", false},
+	}
+
+	for _, tc := range cases {
+		opts, err := ParseOptions(defs, tc.args)
+		if err != nil {
+			t.Fatalf("ParseOptions(%q) error = %v", tc.args, err)
+		}
+		if opts.String("query") != tc.query || opts.Bool("live") != tc.live {
+			t.Errorf("ParseOptions(%q) = %v", tc.args, opts)
+		}
+	}
+}
+
+func TestParseOptionsDuration(t *testing.T) {
+	defs := []SlashOption{{Type: discordgo.ApplicationCommandOptionString, Name: "position", Duration: true}}
+
+	opts, err := ParseOptions(defs, "1m30s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := opts.Duration("position"); d != 90*time.Second {
+		t.Errorf("got %v, want 1m30s", d)
+	}
+
+	if _, err := ParseOptions(defs, "soon"); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+}
@@ -3,26 +3,107 @@ package globals
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/birabittoh/disgord/src/config"
+	"github.com/birabittoh/disgord/src/i18n"
+	"github.com/birabittoh/disgord/src/store"
 	"github.com/birabittoh/miri"
 	"github.com/bwmarrin/discordgo"
+	"github.com/lmittmann/tint"
 )
 
 type UtilsService struct {
-	Session *discordgo.Session
-	Config  *config.Config
-	Ctx     context.Context
+	Session  *discordgo.Session
+	Config   *config.Config
+	Ctx      context.Context
+	Store    *store.Store
+	Settings *store.Collection[GuildSettings]
+
+	Translator *i18n.Translator
+	Logger     *slog.Logger
 }
 
-func NewUtilsService(cfg *config.Config) *UtilsService {
+func NewUtilsService(cfg *config.Config) (*UtilsService, error) {
+	s, err := store.Open(cfg.DataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := store.NewCollection[GuildSettings](s, "settings")
+	if err != nil {
+		return nil, err
+	}
+
+	translator, err := i18n.Load(cfg.LocalesDir)
+	if err != nil {
+		return nil, err
+	}
+
 	return &UtilsService{
-		Session: nil, // to be set later
-		Config:  cfg,
-		Ctx:     context.Background(),
+		Session:    nil, // to be set later
+		Config:     cfg,
+		Ctx:        context.Background(),
+		Store:      s,
+		Settings:   settings,
+		Translator: translator,
+		Logger: slog.New(tint.NewHandler(os.Stdout, &tint.Options{
+			Level:      cfg.LogLevel,
+			TimeFormat: cfg.TimeFormat,
+		})).With("service", LoggerUtils),
+	}, nil
+}
+
+// GuildSettings returns the settings of guildID, or the defaults if it has none.
+func (us *UtilsService) GuildSettings(guildID string) GuildSettings {
+	gs, _ := us.Settings.Get(guildID)
+	return gs
+}
+
+// ModuleEnabled reports whether commands tagged with tag can be used in guildID.
+// Direct messages have no settings, so every module is enabled there.
+func (us *UtilsService) ModuleEnabled(guildID, tag string) bool {
+	return guildID == "" || us.GuildSettings(guildID).ModuleEnabled(tag)
+}
+
+// HasPermissions reports whether the user holds all of perms in channelID.
+// Interaction members carry their computed permissions; message authors are
+// resolved through the state cache.
+func (us *UtilsService) HasPermissions(member *discordgo.Member, channelID, userID string, perms int64) bool {
+	if perms == 0 {
+		return true
 	}
+
+	granted := int64(0)
+	if member != nil && member.Permissions != 0 {
+		granted = member.Permissions
+	} else {
+		var err error
+		granted, err = us.Session.State.UserChannelPermissions(userID, channelID)
+		if err != nil {
+			return false
+		}
+	}
+	return granted&discordgo.PermissionAdministrator != 0 || granted&perms == perms
+}
+
+// GuildLocale returns the language chosen for guildID, or the default one.
+func (us *UtilsService) GuildLocale(guildID string) string {
+	if guildID != "" {
+		if locale := us.GuildSettings(guildID).Locale; locale != "" {
+			return locale
+		}
+	}
+	return us.Config.Locale
+}
+
+// TGuild translates the message key for a whole guild, for messages that are
+// not an answer to anyone.
+func (us *UtilsService) TGuild(guildID, key string, args ...any) string {
+	return us.Translator.Sprintf(us.GuildLocale(guildID), key, args...)
 }
 
 func (us *UtilsService) GetVoiceChannelID(member *discordgo.Member, guildID, authorID string) (response string, g *discordgo.Guild, voiceChannelID string) {
@@ -50,7 +131,7 @@ func (us *UtilsService) GetVoiceChannelID(member *discordgo.Member, guildID, aut
 	return
 }
 
-func (us *UtilsService) FormatHelp(command string, bc BotCommand) string {
+func (us *UtilsService) FormatHelp(locale, command string, bc BotCommand) string {
 	var shortCodeStr string
 	if bc.ShortCode != "" {
 		shortCodeStr = fmt.Sprintf(" (%s)", us.FormatCommand(bc.ShortCode))
@@ -58,7 +139,10 @@ func (us *UtilsService) FormatHelp(command string, bc BotCommand) string {
 	if bc.Alias != "" {
 		shortCodeStr += fmt.Sprintf(" (%s)", us.FormatCommand(bc.Alias))
 	}
-	return fmt.Sprintf(MsgHelpFmt, us.FormatCommand(command)+shortCodeStr, bc.Help)
+	if bc.HasSubcommands() {
+		shortCodeStr += fmt.Sprintf(" [%s]", strings.Join(bc.SubcommandNames(), "|"))
+	}
+	return us.Translator.Sprintf(locale, MsgHelpFmt, us.FormatCommand(command)+shortCodeStr, us.Translator.Message(locale, bc.Help))
 }
 
 func (us *UtilsService) FormatCommand(command string) string {
@@ -70,6 +154,35 @@ func (us *UtilsService) FormatTrackLine(v *miri.SongResult) string {
 	return fmt.Sprintf("%s - **%s** (`%s`)", v.Artist.Name, v.Title, duration.String())
 }
 
+// FormatTrackChoice is FormatTrackLine without markdown, clamped to fit an autocomplete choice.
+func (us *UtilsService) FormatTrackChoice(v *miri.SongResult) string {
+	duration := time.Duration(v.Duration) * time.Second
+	return clamp(fmt.Sprintf("%s - %s (%s)", v.Artist.Name, v.Title, duration.String()), DiscordChoiceLimit)
+}
+
+// TrackSelectOption renders a track as a select menu option with the given value.
+func (us *UtilsService) TrackSelectOption(v *miri.SongResult, value string) discordgo.SelectMenuOption {
+	duration := time.Duration(v.Duration) * time.Second
+	return SelectOption(v.Title, fmt.Sprintf("%s (%s)", v.Artist.Name, duration.String()), value)
+}
+
+// SelectOption builds a select menu option, clamping its texts to fit.
+func SelectOption(label, description, value string) discordgo.SelectMenuOption {
+	return discordgo.SelectMenuOption{
+		Label:       clamp(label, DiscordChoiceLimit),
+		Value:       value,
+		Description: clamp(description, DiscordChoiceLimit),
+	}
+}
+
+// clamp shortens s to at most n runes, marking the cut with an ellipsis.
+func clamp(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
+}
+
 func (us *UtilsService) ParseUserMessage(messageContent string) (command string, args string, ok bool) {
 	after, found := strings.CutPrefix(messageContent, us.Config.Prefix)
 	if !found {
@@ -93,11 +206,83 @@ func (us *UtilsService) EmbedMessage(content string) *discordgo.MessageSend {
 	}
 }
 
+// Reply returns a successful CommandResult with a single embed.
+func (us *UtilsService) Reply(content string) *CommandResult {
+	return us.ReplyMessage(us.EmbedMessage(content))
+}
+
+// ReplyMessage wraps a MessageSend in a successful CommandResult.
+func (us *UtilsService) ReplyMessage(msg *discordgo.MessageSend) *CommandResult {
+	return &CommandResult{Message: msg}
+}
+
+// UserError returns a CommandResult for a request that cannot be fulfilled;
+// it is only shown to the user who made it.
+func (us *UtilsService) UserError(content string) *CommandResult {
+	return &CommandResult{Message: us.EmbedMessage(content), Kind: ResultUserError, Ephemeral: true}
+}
+
+// InternalError logs err, when there is one, and returns a CommandResult with
+// the generic error message.
+func (us *UtilsService) InternalError(err error) *CommandResult {
+	if err != nil {
+		us.Logger.Error("internal error", "error", err)
+	}
+	return &CommandResult{Message: us.EmbedMessage(MsgError), Kind: ResultInternalError, Ephemeral: true}
+}
+
+// WithComponents attaches message components to the reply.
+func (r *CommandResult) WithComponents(components ...discordgo.MessageComponent) *CommandResult {
+	r.Message.Components = components
+	return r
+}
+
 // EmbedTrackMessage returns a MessageSend with an embed and a cover image.
-func (us *UtilsService) EmbedTrackMessage(track *miri.SongResult) *discordgo.MessageSend {
+func (us *UtilsService) EmbedTrackMessage(locale string, track *miri.SongResult) *discordgo.MessageSend {
+	return us.EmbedTrackDetails(locale, track, TrackInfo{})
+}
+
+// TrackInfo is what a track embed shows besides the track itself. Zero fields are left out.
+type TrackInfo struct {
+	Link        string
+	ReleaseDate string
+	Explicit    bool
+	BPM         float64
+	Requester   string
+	Position    int           // in the queue, 0 when playing or not queued
+	ETA         time.Duration // until the track plays
+}
+
+// EmbedTrackDetails is the layout shared by every message about a single track,
+// with its field names in locale.
+func (us *UtilsService) EmbedTrackDetails(locale string, track *miri.SongResult, info TrackInfo) *discordgo.MessageSend {
 	response := us.EmbedMessage(fmt.Sprintf("%s\n\n_%s_", track.Artist.Name, track.Album.Title))
-	response.Embeds[0].Title = track.Title
-	response.Embeds[0].Thumbnail = &discordgo.MessageEmbedThumbnail{URL: track.CoverURL(us.Config.AlbumCoverSize)}
+	embed := response.Embeds[0]
+	embed.Title = track.Title
+	embed.URL = info.Link
+	embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: track.CoverURL(us.Config.AlbumCoverSize)}
+
+	field := func(name, value string) {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: us.Translator.Message(locale, name), Value: value, Inline: true})
+	}
+	if track.Duration > 0 {
+		field(MsgFieldDuration, (time.Duration(track.Duration) * time.Second).String())
+	}
+	if info.ReleaseDate != "" {
+		field(MsgFieldReleased, info.ReleaseDate)
+	}
+	if info.BPM > 0 {
+		field(MsgFieldBPM, fmt.Sprintf("%.0f", info.BPM))
+	}
+	if info.Explicit {
+		field(MsgFieldExplicit, us.Translator.Message(locale, MsgYes))
+	}
+	if info.Requester != "" {
+		field(MsgFieldRequester, fmt.Sprintf("<@%s>", info.Requester))
+	}
+	if info.Position > 0 {
+		field(MsgFieldPosition, us.Translator.Sprintf(locale, MsgPositionFmt, info.Position, info.ETA.Round(time.Second)))
+	}
 	return response
 }
 
@@ -109,28 +294,41 @@ func (us *UtilsService) EmbedToResponse(msg *discordgo.MessageSend) *discordgo.I
 			Content:    msg.Content,
 			Components: msg.Components,
 			Embeds:     msg.Embeds,
+			Files:      msg.Files,
 		},
 	}
 }
 
-// InteractionToMessageCreate converts an InteractionCreate to a MessageCreate.
-func (us *UtilsService) InteractionToMessageCreate(i *discordgo.InteractionCreate, args string) *discordgo.MessageCreate {
-	m := &discordgo.MessageCreate{
-		Message: &discordgo.Message{
-			GuildID:   i.GuildID,
-			ChannelID: i.ChannelID,
-		},
+// ResultToResponse converts a CommandResult to an InteractionResponse.
+func (us *UtilsService) ResultToResponse(r *CommandResult) *discordgo.InteractionResponse {
+	response := us.EmbedToResponse(r.Message)
+	if r.Ephemeral {
+		response.Data.Flags = discordgo.MessageFlagsEphemeral
 	}
-	if i.Member != nil {
-		m.Author = i.Member.User
-		m.Member = i.Member
-	} else if i.User != nil {
-		m.Author = i.User
+	return response
+}
+
+// EmbedToWebhookEdit converts a MessageSend to an edit of a deferred interaction response.
+func (us *UtilsService) EmbedToWebhookEdit(msg *discordgo.MessageSend) *discordgo.WebhookEdit {
+	components := msg.Components
+	if components == nil {
+		components = []discordgo.MessageComponent{}
 	}
 
-	m.Content = args
+	return &discordgo.WebhookEdit{
+		Content:    &msg.Content,
+		Components: &components,
+		Embeds:     &msg.Embeds,
+		Files:      msg.Files,
+	}
+}
 
-	return m
+// InteractionUser returns who triggered i: the member in guilds, the user in DMs.
+func InteractionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
 }
 
 func (us *UtilsService) GetInviteLink() string {
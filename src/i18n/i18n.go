@@ -0,0 +1,117 @@
+// Package i18n translates the bot replies using message catalogs loaded from
+// files. Messages are looked up by their English text, so a missing catalog or
+// translation falls back to English.
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Catalog holds the translations for one locale.
+type Catalog struct {
+	// Messages maps English messages, format verbs included, to their translation.
+	Messages map[string]string `json:"messages"`
+	// Names maps English command, subcommand and option names to their translation.
+	Names map[string]string `json:"names,omitempty"`
+}
+
+// Translator looks messages up in the catalogs of the known locales.
+type Translator struct {
+	catalogs map[string]Catalog // by Discord locale, e.g. "it" or "pt-BR"
+}
+
+// Load reads every <locale>.json file in dir. A missing directory leaves the
+// bot in English only.
+func Load(dir string) (*Translator, error) {
+	t := &Translator{catalogs: map[string]Catalog{}}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.New("could not read catalog: " + err.Error())
+		}
+
+		var c Catalog
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, errors.New("could not parse catalog " + filepath.Base(path) + ": " + err.Error())
+		}
+		t.catalogs[strings.TrimSuffix(filepath.Base(path), ".json")] = c
+	}
+	return t, nil
+}
+
+// Locales lists the locales with a catalog, sorted.
+func (t *Translator) Locales() []string {
+	if t == nil {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(t.catalogs))
+}
+
+// catalog returns the catalog of locale, or of its language without the region.
+func (t *Translator) catalog(locale string) (Catalog, bool) {
+	if t == nil || locale == "" {
+		return Catalog{}, false
+	}
+	if c, ok := t.catalogs[locale]; ok {
+		return c, true
+	}
+	lang, _, _ := strings.Cut(locale, "-")
+	c, ok := t.catalogs[lang]
+	return c, ok
+}
+
+// Message returns the translation of key in locale, or key itself.
+func (t *Translator) Message(locale, key string) string {
+	if c, ok := t.catalog(locale); ok {
+		if msg, ok := c.Messages[key]; ok && msg != "" {
+			return msg
+		}
+	}
+	return key
+}
+
+// Sprintf formats the translation of key in locale with args.
+func (t *Translator) Sprintf(locale, key string, args ...any) string {
+	msg := t.Message(locale, key)
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Localizations returns the translations of key in every locale that has
+// one, as expected by Discord for command names and descriptions. names
+// selects the command names instead of the messages.
+func (t *Translator) Localizations(key string, names bool) map[string]string {
+	if t == nil {
+		return nil
+	}
+
+	l := map[string]string{}
+	for locale, c := range t.catalogs {
+		source := c.Messages
+		if names {
+			source = c.Names
+		}
+		if msg, ok := source[key]; ok && msg != "" {
+			l[locale] = msg
+		}
+	}
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
@@ -0,0 +1,49 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranslator(t *testing.T) {
+	dir := t.TempDir()
+	catalog := `{"messages": {"Skipped.": "Saltato.", "Volume is set to %d%%.": "Il volume è al %d%%."}, "names": {"play": "riproduci"}}`
+	if err := os.WriteFile(filepath.Join(dir, "it.json"), []byte(catalog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		locale, key string
+		args        []any
+		want        string
+	}{
+		{"it", "Skipped.", nil, "Saltato."},
+		{"it", "Volume is set to %d%%.", []any{50}, "Il volume è al 50%."},
+		{"it", "Left.", nil, "Left."},
+		{"en-US", "Skipped.", nil, "Skipped."},
+		{"", "Volume is set to %d%%.", []any{50}, "Volume is set to 50%."},
+	}
+	for _, tc := range cases {
+		if got := tr.Sprintf(tc.locale, tc.key, tc.args...); got != tc.want {
+			t.Errorf("Sprintf(%q, %q) = %q, want %q", tc.locale, tc.key, got, tc.want)
+		}
+	}
+
+	if got := tr.Localizations("play", true); got["it"] != "riproduci" || len(got) != 1 {
+		t.Errorf("Localizations(play) = %v", got)
+	}
+	if got := tr.Localizations("missing", false); got != nil {
+		t.Errorf("Localizations(missing) = %v, want nil", got)
+	}
+
+	var empty *Translator
+	if got := empty.Sprintf("it", "Skipped."); got != "Skipped." {
+		t.Errorf("nil translator returned %q", got)
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"io"
 	"os/exec"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	gl "github.com/birabittoh/disgord/src/globals"
 	"github.com/birabittoh/miri"
@@ -16,12 +19,19 @@ import (
 
 type Audio struct {
 	playing      bool
+	seekTo       int
+	frames       atomic.Int64 // sent by play_sound, read by Position
 	Done         chan error
 	outputChan   chan []byte
 	ffmpegStream io.ReadCloser
 	ffmpegCmd    *exec.Cmd
 	onFinish     func()
 
+	pauseMu  sync.Mutex
+	resumed  chan struct{} // closed by Resume, nil unless paused
+	stopped  chan struct{} // closed by Stop
+	stopOnce sync.Once
+
 	ms *MusicService
 }
 
@@ -30,6 +40,8 @@ func NewAudio(track *miri.SongResult, vc *discordgo.VoiceConnection, ms *MusicSe
 		playing:    true,
 		Done:       make(chan error),
 		outputChan: make(chan []byte, 450),
+		stopped:    make(chan struct{}),
+		seekTo:     seekTo,
 		ms:         ms,
 	}
 
@@ -65,6 +77,11 @@ func (a *Audio) downloader(track *miri.SongResult, seekTo int, guildID string, b
 		"pipe:1",
 	}
 
+	if q := a.ms.GetQueue(guildID); q != nil && q.volume != gl.AudioVolume {
+		filter := "volume=" + strconv.FormatFloat(float64(q.volume)/100, 'f', 2, 64)
+		ffmpegArgs = append([]string{"-af", filter}, ffmpegArgs...)
+	}
+
 	a.ffmpegCmd = exec.Command("ffmpeg", ffmpegArgs...)
 	ffmpegStdin, err := a.ffmpegCmd.StdinPipe()
 	if err != nil {
@@ -152,6 +169,14 @@ func (a *Audio) play_sound(vc *discordgo.VoiceConnection) (err error) {
 	}()
 
 	for a.playing {
+		if resumed := a.pauseChan(); resumed != nil {
+			select {
+			case <-resumed:
+			case <-a.stopped:
+			}
+			continue
+		}
+
 		opus, ok := <-a.outputChan
 		if !ok {
 			a.playing = false
@@ -167,6 +192,7 @@ func (a *Audio) play_sound(vc *discordgo.VoiceConnection) (err error) {
 					}
 				}()
 				vc.OpusSend <- opus
+				a.frames.Add(1)
 			}()
 		}
 	}
@@ -176,6 +202,7 @@ func (a *Audio) play_sound(vc *discordgo.VoiceConnection) (err error) {
 
 func (a *Audio) Stop() {
 	a.playing = false
+	a.stopOnce.Do(func() { close(a.stopped) })
 
 	// Close the ffmpeg stream
 	if a.ffmpegStream != nil {
@@ -189,6 +216,42 @@ func (a *Audio) Stop() {
 	}
 }
 
+// Position reports how far into the track playback currently is.
+func (a *Audio) Position() time.Duration {
+	frame := time.Duration(gl.AudioFrameSize) * time.Second / time.Duration(gl.AudioFrameRate)
+	return time.Duration(a.seekTo)*time.Second + time.Duration(a.frames.Load())*frame
+}
+
+// Pause holds back opus frames until Resume is called; ffmpeg is throttled by
+// the full output buffer in the meantime.
+func (a *Audio) Pause() {
+	a.pauseMu.Lock()
+	defer a.pauseMu.Unlock()
+	if a.resumed == nil {
+		a.resumed = make(chan struct{})
+	}
+}
+
+func (a *Audio) Resume() {
+	a.pauseMu.Lock()
+	defer a.pauseMu.Unlock()
+	if a.resumed != nil {
+		close(a.resumed)
+		a.resumed = nil
+	}
+}
+
+func (a *Audio) Paused() bool {
+	return a.pauseChan() != nil
+}
+
+// pauseChan returns the channel closed on resume, or nil when not paused.
+func (a *Audio) pauseChan() chan struct{} {
+	a.pauseMu.Lock()
+	defer a.pauseMu.Unlock()
+	return a.resumed
+}
+
 func (a *Audio) Monitor() {
 	go func() {
 		if err := <-a.Done; err != nil {
@@ -0,0 +1,90 @@
+package music
+
+import (
+	"strings"
+	"time"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/birabittoh/miri"
+	"github.com/bwmarrin/discordgo"
+)
+
+// nextAutocomplete marks a new autocomplete request for userID and returns its sequence number.
+func (ms *MusicService) nextAutocomplete(userID string) uint64 {
+	ms.acMu.Lock()
+	defer ms.acMu.Unlock()
+	ms.acSeq[userID]++
+	return ms.acSeq[userID]
+}
+
+// isLatestAutocomplete reports whether seq is still the newest request for userID.
+func (ms *MusicService) isLatestAutocomplete(userID string, seq uint64) bool {
+	ms.acMu.Lock()
+	defer ms.acMu.Unlock()
+	if ms.acSeq[userID] != seq {
+		return false
+	}
+	delete(ms.acSeq, userID)
+	return true
+}
+
+func (ms *MusicService) suggestTracks(query string) ([]miri.SongResult, error) {
+	key := strings.ToLower(query)
+	if results, ok := ms.suggestions.Get(key); ok {
+		return results, nil
+	}
+
+	opt := miri.SearchOptions{
+		Limit: gl.DiscordMaxChoices,
+		Query: query,
+		Order: searchOrder,
+	}
+	results, err := miri.SearchTracks(ms.us.Ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	ms.suggestions.Add(key, results)
+	return results, nil
+}
+
+// HandleTrackAutocomplete suggests tracks for a partially typed query. Keystrokes
+// are debounced per user: ok is false when a newer request superseded this one
+// and no response should be sent.
+func (ms *MusicService) HandleTrackAutocomplete(query string, i *discordgo.InteractionCreate) (choices []*discordgo.ApplicationCommandOptionChoice, ok bool) {
+	choices = []*discordgo.ApplicationCommandOptionChoice{}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return choices, true
+	}
+
+	var userID string
+	if user := gl.InteractionUser(i); user != nil {
+		userID = user.ID
+	}
+
+	seq := ms.nextAutocomplete(userID)
+	time.Sleep(gl.AutocompleteDebounce)
+	if !ms.isLatestAutocomplete(userID, seq) {
+		return nil, false
+	}
+
+	results, err := ms.suggestTracks(query)
+	if err != nil {
+		ms.Logger.Error("could not search track", "error", err)
+		return choices, true
+	}
+
+	for _, v := range results[:min(len(results), gl.DiscordMaxChoices)] {
+		value := v.Artist.Name + " " + v.Title
+		if runes := []rune(value); len(runes) > gl.DiscordChoiceLimit {
+			value = string(runes[:gl.DiscordChoiceLimit])
+		}
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  ms.us.FormatTrackChoice(&v),
+			Value: value,
+		})
+	}
+	return choices, true
+}
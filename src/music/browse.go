@@ -0,0 +1,198 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/birabittoh/disgord/src/deezer"
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/birabittoh/miri"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Kinds of catalog entries that can be searched and browsed.
+const (
+	KindAlbum    = "album"
+	KindArtist   = "artist"
+	KindPlaylist = "playlist"
+)
+
+const (
+	browseInteraction     = "browse"
+	playTracksInteraction = "play_tracks"
+	browseShown           = gl.DiscordMaxChoices // songs listed when browsing, as many as a select menu holds
+	browseMaxTracks       = 100                  // songs added at once from an artist or playlist
+)
+
+var errUnknownKind = errors.New("unknown catalog kind")
+
+// catalogEntry is an album, artist or playlist in the search results.
+type catalogEntry struct {
+	id     string
+	name   string
+	detail string
+}
+
+func (ms *MusicService) HandleSearchAlbum(ctx *gl.CommandContext) *gl.CommandResult {
+	return ms.searchCatalog(KindAlbum, ctx.Options.String("query"), ctx)
+}
+
+func (ms *MusicService) HandleSearchArtist(ctx *gl.CommandContext) *gl.CommandResult {
+	return ms.searchCatalog(KindArtist, ctx.Options.String("query"), ctx)
+}
+
+func (ms *MusicService) HandleSearchPlaylist(ctx *gl.CommandContext) *gl.CommandResult {
+	return ms.searchCatalog(KindPlaylist, ctx.Options.String("query"), ctx)
+}
+
+// searchCatalog lists the albums, artists or playlists matching query, with a
+// select menu to browse the songs of one of them.
+func (ms *MusicService) searchCatalog(kind, query string, ctx *gl.CommandContext) *gl.CommandResult {
+	if query == "" {
+		return ctx.UserError(gl.MsgNoKeywords)
+	}
+
+	// entries are picked from a select menu, which holds a limited number of options
+	limit := min(int(ms.us.Config.MaxSearchResults), gl.DiscordMaxChoices)
+	entries, err := ms.findCatalog(kind, query, limit, ctx.Locale())
+	if err != nil {
+		return ms.us.InternalError(errors.New("could not search " + kind + " catalog: " + err.Error()))
+	}
+	if len(entries) == 0 {
+		return ctx.UserError(gl.MsgNoResults)
+	}
+
+	var out string
+	options := make([]discordgo.SelectMenuOption, 0, len(entries))
+	for n, e := range entries {
+		out += fmt.Sprintf(gl.MsgOrderedList, n+1, ctx.T(gl.MsgCatalogLineFmt, e.name, e.detail))
+		options = append(options, gl.SelectOption(e.name, e.detail, e.id))
+	}
+
+	return ms.us.Reply(out).WithComponents(discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.SelectMenu{
+			MenuType:    discordgo.StringSelectMenu,
+			CustomID:    browseInteraction + ":" + kind,
+			Placeholder: ctx.T(gl.MsgBrowsePick),
+			Options:     options,
+		},
+	}})
+}
+
+func (ms *MusicService) findCatalog(kind, query string, limit int, locale string) ([]catalogEntry, error) {
+	var entries []catalogEntry
+	switch kind {
+	case KindAlbum:
+		albums, err := deezer.SearchAlbums(ms.us.Ctx, query, limit)
+		for _, a := range albums {
+			entries = append(entries, catalogEntry{fmt.Sprint(a.ID), a.Title, ms.us.Translator.Sprintf(locale, gl.MsgAlbumDetailFmt, a.Artist.Name, a.NbTracks)})
+		}
+		return entries, err
+	case KindArtist:
+		artists, err := deezer.SearchArtists(ms.us.Ctx, query, limit)
+		for _, a := range artists {
+			entries = append(entries, catalogEntry{fmt.Sprint(a.ID), a.Name, ms.us.Translator.Sprintf(locale, gl.MsgArtistDetailFmt, a.NbFans)})
+		}
+		return entries, err
+	case KindPlaylist:
+		playlists, err := deezer.SearchPlaylists(ms.us.Ctx, query, limit)
+		for _, p := range playlists {
+			entries = append(entries, catalogEntry{fmt.Sprint(p.ID), p.Title, ms.us.Translator.Sprintf(locale, gl.MsgAlbumDetailFmt, p.User.Name, p.NbTracks)})
+		}
+		return entries, err
+	}
+	return nil, errUnknownKind
+}
+
+// catalogTracks returns the name and songs of an album, the top songs of an
+// artist or the songs of a playlist.
+func (ms *MusicService) catalogTracks(kind, id string) (string, []miri.SongResult, error) {
+	switch kind {
+	case KindAlbum:
+		return deezer.AlbumTracks(ms.us.Ctx, id)
+	case KindArtist:
+		return deezer.ArtistTopTracks(ms.us.Ctx, id, browseShown)
+	case KindPlaylist:
+		return deezer.PlaylistTracks(ms.us.Ctx, id, browseMaxTracks)
+	}
+	return "", nil, errUnknownKind
+}
+
+// HandleBrowse shows the songs of the entry picked from catalog search results;
+// arg is its kind. Songs can be added one at a time or all together.
+func (ms *MusicService) HandleBrowse(arg string, ctx *gl.CommandContext) *gl.CommandResult {
+	i := ctx.Interaction
+	values := i.MessageComponentData().Values
+	if len(values) == 0 {
+		return ctx.UserError(gl.MsgInvalidTrackNumber)
+	}
+	id := values[0]
+
+	name, tracks, err := ms.catalogTracks(arg, id)
+	if err != nil {
+		return ms.us.InternalError(errors.New("could not browse " + arg + " " + id + ": " + err.Error()))
+	}
+	if len(tracks) == 0 {
+		return ctx.UserError(gl.MsgNoResults)
+	}
+
+	shown := tracks[:min(len(tracks), browseShown)]
+	var out strings.Builder
+	options := make([]discordgo.SelectMenuOption, 0, len(shown))
+	for n, track := range shown {
+		trackID := fmt.Sprint(track.ID)
+		ms.tracks.Add(trackID, track) // so that picking it needs no lookup
+		fmt.Fprintf(&out, gl.MsgOrderedList, n+1, ms.us.FormatTrackLine(&track))
+		// values are numbered, as the same song may be listed more than once
+		options = append(options, ms.us.TrackSelectOption(&track, fmt.Sprintf("%d:%s", n, trackID)))
+	}
+	if more := len(tracks) - len(shown); more > 0 {
+		out.WriteString(ctx.T(gl.MsgMoreTracksFmt, more))
+	}
+
+	response := ms.us.EmbedMessage(out.String())
+	response.Embeds[0].Title = name
+	response.Components = []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				MenuType:    discordgo.StringSelectMenu,
+				CustomID:    playTrackInteraction + ":select",
+				Placeholder: ctx.T(gl.MsgChooseTrack),
+				Options:     options,
+			},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    ctx.T(gl.MsgAddAllFmt, len(tracks)),
+				Style:    discordgo.PrimaryButton,
+				CustomID: playTracksInteraction + ":" + arg + ":" + id,
+			},
+		}},
+	}
+	return ms.us.ReplyMessage(response)
+}
+
+// HandlePlayTracks enqueues all the songs of a catalog entry; arg is its kind and ID.
+func (ms *MusicService) HandlePlayTracks(arg string, ctx *gl.CommandContext) *gl.CommandResult {
+	if ctx.Member == nil {
+		return ctx.UserError(gl.MsgUseInServer)
+	}
+
+	kind, id, _ := strings.Cut(arg, ":")
+	name, tracks, err := ms.catalogTracks(kind, id)
+	if err != nil {
+		return ms.us.InternalError(errors.New("could not browse " + kind + " " + id + ": " + err.Error()))
+	}
+	if len(tracks) == 0 {
+		return ctx.UserError(gl.MsgNoResults)
+	}
+
+	q, r := ms.joinQueue(ctx)
+	if r != nil {
+		return r
+	}
+
+	q.AddTracks(ms, tracks, ctx.Author.ID)
+	return ms.us.Reply(ctx.T(gl.MsgAddedFromFmt, len(tracks), name))
+}
@@ -1,22 +1,59 @@
 package music
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/birabittoh/disgord/src/deezer"
 	gl "github.com/birabittoh/disgord/src/globals"
 	"github.com/birabittoh/miri"
 	"github.com/bwmarrin/discordgo"
 )
 
-const searchOrder = "RATING_DESC"
+const (
+	searchOrder          = "RATING_DESC"
+	playTrackInteraction = "play_track"
+)
+
+var linkRegex = regexp.MustCompile(`https?://\S+`)
 
 func getPendingSearchKey(channelID, authorID string) string {
 	return channelID + ":" + authorID
 }
 
-func (ms *MusicService) PlayToVC(query string, vc string, guildID string) (response string, track *miri.SongResult, err error) {
+// findTrack resolves query to a single track. Deezer track links are looked up
+// directly, anything else is searched. It returns nil when nothing matches.
+func (ms *MusicService) findTrack(query string) (*miri.SongResult, error) {
+	if link := linkRegex.FindString(query); link != "" {
+		kind, id, err := deezer.ParseLink(ms.us.Ctx, link)
+		if err == nil && kind == "track" {
+			return deezer.GetTrack(ms.us.Ctx, id)
+		}
+	}
+
+	opt := miri.SearchOptions{
+		Limit: 1,
+		Query: query,
+		Order: searchOrder,
+	}
+	results, err := miri.SearchTracks(ms.us.Ctx, opt)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+// PlayToVC searches query and enqueues the first result at position, or at the
+// end when it is 0; textChannelID is where the now-playing message goes and may
+// be empty to keep the current one. requester is recorded in the listening
+// history and may be empty as well. queued is where the track ended up, 0 when
+// it is playing right away.
+func (ms *MusicService) PlayToVC(query string, vc string, guildID string, textChannelID string, requester string, position int) (response string, track *miri.SongResult, queued int, err error) {
 	voice, err := ms.GetVoiceConnection(vc, guildID)
 	if err != nil {
 		return
@@ -30,12 +67,7 @@ func (ms *MusicService) PlayToVC(query string, vc string, guildID string) (respo
 		return
 	}
 
-	opt := miri.SearchOptions{
-		Limit: 1,
-		Query: query,
-		Order: searchOrder,
-	}
-	results, err := miri.SearchTracks(ms.us.Ctx, opt)
+	track, err = ms.findTrack(query)
 	if err != nil {
 		ms.Logger.Error("could not search track", "error", err)
 		if q.nowPlaying == nil {
@@ -45,7 +77,7 @@ func (ms *MusicService) PlayToVC(query string, vc string, guildID string) (respo
 		return
 	}
 
-	if len(results) == 0 {
+	if track == nil {
 		if q.nowPlaying == nil {
 			voice.Disconnect(ms.us.Ctx)
 		}
@@ -53,282 +85,530 @@ func (ms *MusicService) PlayToVC(query string, vc string, guildID string) (respo
 		return
 	}
 
-	track = &results[0]
-	q.AddTrack(ms, track)
+	ms.setNowPlayingChannel(q, textChannelID)
+	queued = q.InsertTracks(ms, []miri.SongResult{*track}, requester, position)
 	return
 }
 
-func (ms *MusicService) HandlePlay(args string, m *discordgo.MessageCreate) *discordgo.MessageSend {
-	r, _, vc := ms.us.GetVoiceChannelID(m.Member, m.GuildID, m.Author.ID)
+func (ms *MusicService) HandlePlay(ctx *gl.CommandContext) *gl.CommandResult {
+	position, _ := ctx.Options.Int("position")
+	return ms.play(ctx.Options, ctx, int(position))
+}
+
+// HandlePlayNext adds a song right after the current one.
+func (ms *MusicService) HandlePlayNext(ctx *gl.CommandContext) *gl.CommandResult {
+	return ms.play(ctx.Options, ctx, 1)
+}
+
+func (ms *MusicService) play(opts gl.CommandOptions, ctx *gl.CommandContext, position int) *gl.CommandResult {
+	r, _, vc := ms.us.GetVoiceChannelID(ctx.Member, ctx.GuildID, ctx.Author.ID)
 	if r != "" {
-		return ms.us.EmbedMessage(r)
+		return ctx.UserError(r)
+	}
+
+	query := opts.String("query")
+	if len(query) == 0 {
+		return ctx.UserError(gl.MsgNoKeywords)
+	}
+
+	response, track, queued, err := ms.PlayToVC(query, vc, ctx.GuildID, ctx.ChannelID, ctx.Author.ID, position)
+	if err != nil {
+		return ms.us.InternalError(err)
 	}
 
-	if len(args) == 0 {
-		return ms.us.EmbedMessage(gl.MsgNoKeywords)
+	if track == nil {
+		return ctx.UserError(response)
+	}
+
+	return ms.us.ReplyMessage(ms.trackMessage(ctx.Locale(), track, ms.GetQueue(ctx.GuildID), queued))
+}
+
+func (ms *MusicService) HandleSearch(ctx *gl.CommandContext) *gl.CommandResult {
+	query := ctx.Options.String("query")
+	if query == "" {
+		return ctx.UserError(gl.MsgNoKeywords)
+	}
+	if kind := ctx.Options.String("type"); kind != "" {
+		return ms.searchCatalog(kind, query, ctx)
+	}
+
+	return ms.search(query, int(ms.us.Config.MaxSearchResults), false, ctx)
+}
+
+// SearchForm opens the search modal when /search is used without a query.
+func (ms *MusicService) SearchForm(opts gl.CommandOptions) *discordgo.InteractionResponseData {
+	if opts.Has("query") || opts.Has("type") {
+		return nil
 	}
+	return gl.ModalForm("search:", gl.MsgSearchTitle, ms.searchFormOptions())
+}
 
-	response, track, err := ms.PlayToVC(args, vc, m.GuildID)
+// HandleSearchForm runs the search submitted through the search modal.
+func (ms *MusicService) HandleSearchForm(arg string, ctx *gl.CommandContext) *gl.CommandResult {
+	i := ctx.Interaction
+	opts, err := gl.OptionsFromModal(ms.searchFormOptions(), i.ModalSubmitData())
 	if err != nil {
-		return ms.us.EmbedMessage(gl.MsgError)
+		return ms.us.UserError(ctx.TError(err))
 	}
 
-	if track != nil {
-		return ms.us.EmbedTrackMessage(track)
+	count := int(ms.us.Config.MaxSearchResults)
+	if c, ok := opts.Int("count"); ok {
+		count = int(c)
 	}
 
-	return ms.us.EmbedMessage(response)
+	return ms.search(opts.String("query"), count, opts.Bool("all"), ctx)
 }
 
-func (ms *MusicService) HandleSearch(args string, m *discordgo.MessageCreate) *discordgo.MessageSend {
-	if args == "" {
-		return ms.us.EmbedMessage(gl.MsgNoKeywords)
+func (ms *MusicService) searchFormOptions() []gl.SlashOption {
+	minCount := 1.0
+	return []gl.SlashOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "query", Description: "Song to look for", Required: true},
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "count", Description: "Number of results", MinValue: &minCount, MaxValue: float64(ms.us.Config.MaxSearchResults)},
+		{Type: discordgo.ApplicationCommandOptionBoolean, Name: "all", Description: "Add all results to the queue?"},
 	}
+}
 
+// search looks up to count tracks matching query. They are offered as choices,
+// or all added to the queue when addAll is set.
+func (ms *MusicService) search(query string, count int, addAll bool, ctx *gl.CommandContext) *gl.CommandResult {
 	opt := miri.SearchOptions{
 		Index:  0,
-		Limit:  uint64(ms.us.Config.MaxSearchResults),
+		Limit:  uint64(count),
 		Order:  searchOrder,
 		Strict: true,
-		Query:  args,
+		Query:  query,
 	}
 	results, err := miri.SearchTracks(ms.us.Ctx, opt)
 	if err != nil {
-		ms.Logger.Error("could not search track", "error", err)
-		return ms.us.EmbedMessage(gl.MsgError)
+		return ms.us.InternalError(errors.New("could not search track: " + err.Error()))
 	}
 
 	if len(results) == 0 {
-		return ms.us.EmbedMessage(gl.MsgNoResults)
+		return ctx.UserError(gl.MsgNoResults)
 	}
 
-	maxResults := min(len(results), int(ms.us.Config.MaxSearchResults))
-	var out string
-	var buttons []discordgo.MessageComponent
+	maxResults := min(len(results), count)
+	key := getPendingSearchKey(ctx.ChannelID, ctx.Author.ID)
+	if old, ok := ms.Searches.Get(key); ok && old.MessageID != "" {
+		ms.us.Session.ChannelMessageDelete(ctx.ChannelID, old.MessageID)
+	}
+
+	if addAll {
+		ms.Searches.Remove(key)
+		q, r := ms.joinQueue(ctx)
+		if r != nil {
+			return r
+		}
 
+		q.AddTracks(ms, results[:maxResults], ctx.Author.ID)
+		return ms.us.Reply(ctx.T(gl.MsgAddedTracksFmt, maxResults))
+	}
+
+	// only as many as a select menu holds are offered as choices
+	maxResults = min(maxResults, gl.DiscordMaxChoices)
+	var out string
+	options := make([]discordgo.SelectMenuOption, 0, maxResults)
 	for i := range maxResults {
 		v := results[i]
 		out += fmt.Sprintf(gl.MsgOrderedList, i+1, ms.us.FormatTrackLine(&v))
-
-		buttons = append(buttons, discordgo.Button{
-			Label:    fmt.Sprintf("%d", i+1),
-			Style:    discordgo.PrimaryButton,
-			CustomID: fmt.Sprintf("choose_track:%d", i+1),
-		})
+		options = append(options, ms.us.TrackSelectOption(&v, strconv.Itoa(i+1)))
 	}
 
-	// add cancel button
-	buttons = append(buttons, discordgo.Button{
-		Label:    "Cancel",
-		Style:    discordgo.DangerButton,
-		CustomID: "choose_track:0",
-	})
+	ms.Searches.Add(key, &PendingSearch{Results: results[:maxResults]})
+
+	minValues := 1
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				MenuType:    discordgo.StringSelectMenu,
+				CustomID:    "choose_track:select",
+				Placeholder: ctx.T(gl.MsgChooseTracks),
+				MinValues:   &minValues,
+				MaxValues:   maxResults,
+				Options:     options,
+			},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    ctx.T(gl.MsgCancel),
+				Style:    discordgo.DangerButton,
+				CustomID: "choose_track:cancel",
+			},
+		}},
+	}
+
+	return ms.us.Reply(out).WithComponents(components...)
+}
 
-	key := getPendingSearchKey(m.ChannelID, m.Author.ID)
-	if old, ok := ms.Searches.Get(key); ok && old.MessageID != "" {
-		ms.us.Session.ChannelMessageDelete(m.ChannelID, old.MessageID)
+// HandleLyrics shows the lyrics of the song matching the query, or of the current one.
+func (ms *MusicService) HandleLyrics(ctx *gl.CommandContext) *gl.CommandResult {
+	if ctx.Options.Bool("live") {
+		return ms.HandleLyricsLive(ctx)
+	}
+	if ctx.Options.String("query") != "" {
+		return ms.HandleLyricsSearch(ctx)
 	}
-	ms.Searches.Add(key, &PendingSearch{Results: results[:maxResults]})
 
-	// Split buttons into rows of max 5
-	var components []discordgo.MessageComponent
-	for i := 0; i < len(buttons); i += 5 {
-		end := min(i+5, len(buttons))
-		row := discordgo.ActionsRow{
-			Components: buttons[i:end],
-		}
-		components = append(components, row)
+	q := ms.GetQueue(ctx.GuildID)
+	if q == nil || q.nowPlaying == nil {
+		return ctx.UserError(gl.MsgNothingIsPlaying)
 	}
 
-	msg := ms.us.EmbedMessage(out)
-	msg.Components = components
-	return msg
+	return ms.lyricsResult(q.nowPlaying, ctx)
 }
 
-func (ms *MusicService) HandleLyrics(args string, m *discordgo.MessageCreate) *discordgo.MessageSend {
-	q := ms.GetQueue(m.GuildID)
-	if q == nil || q.nowPlaying == nil {
-		return ms.us.EmbedMessage(gl.MsgNothingIsPlaying)
+// HandleLyricsSearch shows the lyrics of the song best matching the given text.
+func (ms *MusicService) HandleLyricsSearch(ctx *gl.CommandContext) *gl.CommandResult {
+	query := []rune(ctx.Options.String("query"))
+	if len(query) == 0 {
+		return ctx.UserError(gl.MsgNoKeywords)
 	}
 
-	lyrics, err := q.nowPlaying.Lyrics(ms.us.Ctx)
-	if err != nil || lyrics == "" {
-		ms.Logger.Error("could not fetch lyrics", "error", err)
-		return ms.us.EmbedMessage(gl.MsgNoLyrics)
+	track, err := ms.findTrack(string(query[:min(len(query), gl.DiscordChoiceLimit)]))
+	if err != nil {
+		return ctx.InternalError(errors.New("could not search track: " + err.Error()))
+	}
+	if track == nil {
+		return ctx.UserError(gl.MsgNoResults)
 	}
 
-	if len(lyrics) > gl.DiscordEmbedDescriptionLimit { // quick bytes check
-		runes := []rune(lyrics)
-		if len(runes) > gl.DiscordEmbedDescriptionLimit { // accurate rune check
-			lyrics = string(runes[:gl.DiscordEmbedDescriptionLimit-1]) + "…"
-		}
+	return ms.lyricsResult(track, ctx)
+}
+
+// HandleListening shows what the given user is listening to through the bot.
+func (ms *MusicService) HandleListening(ctx *gl.CommandContext) *gl.CommandResult {
+	userID := ctx.Options.User("user")
+	_, _, vc := ms.us.GetVoiceChannelID(ctx.Member, ctx.GuildID, userID)
+
+	q := ms.GetQueue(ctx.GuildID)
+	if vc == "" || q == nil || q.nowPlaying == nil || vc != q.VoiceChannelID() {
+		return ctx.UserError(gl.MsgNotListeningFmt, userID)
 	}
 
-	response := ms.us.EmbedTrackMessage(q.nowPlaying)
-	response.Embeds[0].Description = lyrics
+	response := ms.trackMessage(ctx.Locale(), q.nowPlaying, q, 0)
+	response.Content = ctx.T(gl.MsgListeningFmt, userID)
+	response.AllowedMentions = &discordgo.MessageAllowedMentions{}
 
-	return response
+	result := ms.us.ReplyMessage(response)
+	result.Ephemeral = true
+	return result
 }
 
-func (ms *MusicService) HandleSkip(args string, m *discordgo.MessageCreate) *discordgo.MessageSend {
-	r, g, vc := ms.us.GetVoiceChannelID(m.Member, m.GuildID, m.Author.ID)
+func (ms *MusicService) HandleSkip(ctx *gl.CommandContext) *gl.CommandResult {
+	r, g, vc := ms.us.GetVoiceChannelID(ctx.Member, ctx.GuildID, ctx.Author.ID)
 	if r != "" {
-		return ms.us.EmbedMessage(r)
+		return ctx.UserError(r)
 	}
 
 	q := ms.GetQueue(g.ID)
 	if q == nil {
-		return ms.us.EmbedMessage(gl.MsgNothingIsPlaying)
+		return ctx.UserError(gl.MsgNothingIsPlaying)
 	}
 
 	if vc != q.VoiceChannelID() {
-		return ms.us.EmbedMessage(gl.MsgSameVoiceChannel)
+		return ctx.UserError(gl.MsgSameVoiceChannel)
 	}
 
 	err := q.PlayNext(ms, true)
 	if err != nil {
-		return ms.us.EmbedMessage(gl.MsgNothingIsPlaying)
+		return ctx.UserError(gl.MsgNothingIsPlaying)
 	}
 
-	return ms.us.EmbedMessage(gl.MsgSkipped)
+	return ctx.Reply(gl.MsgSkipped)
 }
 
-func (ms *MusicService) HandleQueue(args string, m *discordgo.MessageCreate) *discordgo.MessageSend {
-	q := ms.GetQueue(m.GuildID)
-	if q == nil {
-		return ms.us.EmbedMessage(gl.MsgNothingIsPlaying)
+// HandlePrevious goes back to the last played track.
+func (ms *MusicService) HandlePrevious(ctx *gl.CommandContext) *gl.CommandResult {
+	q, r := ms.controlledQueue(ctx.Member, ctx.GuildID, ctx.Author.ID)
+	if r != "" {
+		return ctx.UserError(r)
+	}
+
+	track, ok, err := q.Previous(ms)
+	if !ok {
+		return ctx.UserError(gl.MsgNoPrevious)
+	}
+	if err != nil {
+		return ctx.InternalError(errors.New("could not play previous track: " + err.Error()))
+	}
+
+	return ctx.Reply(gl.MsgPreviousFmt, ms.us.FormatTrackLine(&track))
+}
+
+// HandleReplay restarts the current track.
+func (ms *MusicService) HandleReplay(ctx *gl.CommandContext) *gl.CommandResult {
+	q, r := ms.controlledQueue(ctx.Member, ctx.GuildID, ctx.Author.ID)
+	if r != "" {
+		return ctx.UserError(r)
+	}
+
+	if q.nowPlaying == nil {
+		return ctx.UserError(gl.MsgNothingIsPlaying)
+	}
+
+	err := q.Replay(ms)
+	if err != nil {
+		return ctx.InternalError(errors.New("could not replay: " + err.Error()))
+	}
+
+	return ctx.Reply(gl.MsgReplaying)
+}
+
+// HandleQueue shows the current track in full, followed by the upcoming ones
+// and when they play.
+func (ms *MusicService) HandleQueue(ctx *gl.CommandContext) *gl.CommandResult {
+	q := ms.GetQueue(ctx.GuildID)
+	if q == nil || q.nowPlaying == nil {
+		return ctx.UserError(gl.MsgNothingIsPlaying)
+	}
+
+	response := ms.trackMessage(ctx.Locale(), q.nowPlaying, q, 0)
+	response.Embeds[0].Author = &discordgo.MessageEmbedAuthor{Name: ctx.T(gl.MsgNowPlaying)}
+	if len(q.items) == 0 {
+		return ms.us.ReplyMessage(response)
 	}
 
 	var out string
-	tracks := q.Tracks()
-	for i, v := range tracks {
-		out += fmt.Sprintf(gl.MsgOrderedList, i, ms.us.FormatTrackLine(&v))
+	eta := q.ETA(1)
+	for n, item := range q.items {
+		line := fmt.Sprintf(gl.MsgOrderedList, n+1, ms.us.FormatTrackLine(&item.track)+ctx.T(gl.MsgETAFmt, eta.Round(time.Second)))
+		if len(out)+len(line) > gl.DiscordEmbedDescriptionLimit-len(gl.MsgMoreTracksFmt) {
+			out += ctx.T(gl.MsgMoreTracksFmt, len(q.items)-n)
+			break
+		}
+		out += line
+		eta += time.Duration(item.track.Duration) * time.Second
 	}
-	return ms.us.EmbedMessage(out)
+
+	upcoming := ms.us.EmbedMessage(out).Embeds[0]
+	upcoming.Title = ctx.T(gl.MsgUpNextFmt, len(q.items))
+	response.Embeds = append(response.Embeds, upcoming)
+	return ms.us.ReplyMessage(response)
 }
 
-func (ms *MusicService) HandleClear(args string, m *discordgo.MessageCreate) *discordgo.MessageSend {
-	r, g, vc := ms.us.GetVoiceChannelID(m.Member, m.GuildID, m.Author.ID)
+func (ms *MusicService) HandleRemove(ctx *gl.CommandContext) *gl.CommandResult {
+	q, r := ms.controlledQueue(ctx.Member, ctx.GuildID, ctx.Author.ID)
 	if r != "" {
-		return ms.us.EmbedMessage(r)
+		return ctx.UserError(r)
+	}
+
+	index, _ := ctx.Options.Int("index")
+	track, ok := q.Remove(int(index))
+	if !ok {
+		return ctx.UserError(gl.MsgInvalidTrackNumber)
+	}
+
+	return ctx.Reply(gl.MsgRemovedFmt, ms.us.FormatTrackLine(&track))
+}
+
+func (ms *MusicService) HandleVolume(ctx *gl.CommandContext) *gl.CommandResult {
+	r, g, vc := ms.us.GetVoiceChannelID(ctx.Member, ctx.GuildID, ctx.Author.ID)
+	if r != "" {
+		return ctx.UserError(r)
 	}
 
 	q := ms.GetQueue(g.ID)
 	if q == nil {
-		return ms.us.EmbedMessage(gl.MsgNothingIsPlaying)
+		return ctx.UserError(gl.MsgNothingIsPlaying)
+	}
+
+	level, ok := ctx.Options.Int("level")
+	if !ok {
+		return ctx.Reply(gl.MsgVolumeFmt, q.Volume())
 	}
 
 	if vc != q.VoiceChannelID() {
-		return ms.us.EmbedMessage(gl.MsgSameVoiceChannel)
+		return ctx.UserError(gl.MsgSameVoiceChannel)
 	}
 
-	q.Clear()
+	err := q.SetVolume(ms, int(level))
+	if err != nil {
+		return ctx.InternalError(errors.New("could not set volume: " + err.Error()))
+	}
 
-	return ms.us.EmbedMessage(gl.MsgCleared)
+	return ctx.Reply(gl.MsgVolumeFmt, q.Volume())
 }
 
-func (ms *MusicService) HandleLeave(args string, m *discordgo.MessageCreate) *discordgo.MessageSend {
-	r, g, vc := ms.us.GetVoiceChannelID(m.Member, m.GuildID, m.Author.ID)
+func (ms *MusicService) HandleClear(ctx *gl.CommandContext) *gl.CommandResult {
+	r, g, vc := ms.us.GetVoiceChannelID(ctx.Member, ctx.GuildID, ctx.Author.ID)
 	if r != "" {
-		return ms.us.EmbedMessage(r)
+		return ctx.UserError(r)
 	}
 
 	q := ms.GetQueue(g.ID)
 	if q == nil {
-		return ms.us.EmbedMessage(gl.MsgNothingIsPlaying)
+		return ctx.UserError(gl.MsgNothingIsPlaying)
 	}
 
 	if vc != q.VoiceChannelID() {
-		return ms.us.EmbedMessage(gl.MsgSameVoiceChannel)
+		return ctx.UserError(gl.MsgSameVoiceChannel)
 	}
 
-	ms.DeleteQueue(g.ID)
-	return ms.us.EmbedMessage(gl.MsgLeft)
+	q.Clear()
+
+	return ctx.Reply(gl.MsgCleared)
 }
 
-func (ms *MusicService) HandleSeek(args string, m *discordgo.MessageCreate) *discordgo.MessageSend {
-	r, g, vc := ms.us.GetVoiceChannelID(m.Member, m.GuildID, m.Author.ID)
+func (ms *MusicService) HandleLeave(ctx *gl.CommandContext) *gl.CommandResult {
+	r, g, vc := ms.us.GetVoiceChannelID(ctx.Member, ctx.GuildID, ctx.Author.ID)
 	if r != "" {
-		return ms.us.EmbedMessage(r)
+		return ctx.UserError(r)
 	}
 
-	if args == "" {
-		return ms.us.EmbedMessage(gl.MsgNoKeywords)
+	q := ms.GetQueue(g.ID)
+	if q == nil {
+		return ctx.UserError(gl.MsgNothingIsPlaying)
 	}
 
-	seekTo, err := time.ParseDuration(args)
-	if err != nil {
-		return ms.us.EmbedMessage(gl.MsgInvalidSeekTime)
+	if vc != q.VoiceChannelID() {
+		return ctx.UserError(gl.MsgSameVoiceChannel)
+	}
+
+	ms.DeleteQueue(g.ID)
+	return ctx.Reply(gl.MsgLeft)
+}
+
+func (ms *MusicService) HandleSeek(ctx *gl.CommandContext) *gl.CommandResult {
+	r, g, vc := ms.us.GetVoiceChannelID(ctx.Member, ctx.GuildID, ctx.Author.ID)
+	if r != "" {
+		return ctx.UserError(r)
+	}
+
+	seekTo, ok := ctx.Options.Duration("position")
+	if !ok {
+		return ctx.UserError(gl.MsgInvalidSeekTime)
 	}
 
 	q := ms.GetQueue(g.ID)
 	if q == nil {
-		return ms.us.EmbedMessage(gl.MsgNothingIsPlaying)
+		return ctx.UserError(gl.MsgNothingIsPlaying)
 	}
 
 	if vc != q.VoiceChannelID() {
-		return ms.us.EmbedMessage(gl.MsgSameVoiceChannel)
+		return ctx.UserError(gl.MsgSameVoiceChannel)
 	}
 
 	np := q.nowPlaying
 	if np == nil {
-		return ms.us.EmbedMessage(gl.MsgNothingIsPlaying)
+		return ctx.UserError(gl.MsgNothingIsPlaying)
 	}
 
 	seekToSeconds := int(seekTo.Seconds())
 	if seekToSeconds < 0 || seekToSeconds >= np.Duration {
-		return ms.us.EmbedMessage(gl.MsgInvalidSeekTime)
+		return ctx.UserError(gl.MsgInvalidSeekTime)
 	}
 
-	err = q.Seek(ms, seekToSeconds)
+	err := q.Seek(ms, seekToSeconds)
 	if err != nil {
-		ms.Logger.Error("could not seek", "error", err)
-		return ms.us.EmbedMessage(gl.MsgError)
+		return ctx.InternalError(errors.New("could not seek: " + err.Error()))
 	}
 
-	return ms.us.EmbedMessage(fmt.Sprintf(gl.MsgSeeked, seekTo.String()))
+	return ctx.Reply(gl.MsgSeeked, seekTo.String())
 }
 
-func (ms *MusicService) HandleChooseTrack(arg string, i *discordgo.InteractionCreate) *discordgo.MessageSend {
-	trackIdx, err := strconv.Atoi(arg)
-	if err != nil || trackIdx < 0 {
-		return ms.us.EmbedMessage(gl.MsgInvalidTrackNumber)
-	}
-
-	key := getPendingSearchKey(i.ChannelID, i.Member.User.ID)
+// HandleChooseTrack enqueues the tracks picked from the search results, in the
+// order they were listed, or cancels the search.
+func (ms *MusicService) HandleChooseTrack(arg string, ctx *gl.CommandContext) *gl.CommandResult {
+	i := ctx.Interaction
+	key := getPendingSearchKey(i.ChannelID, ctx.Author.ID)
 	ps, found := ms.Searches.Get(key)
-	if !found || trackIdx > len(ps.Results) {
-		return ms.us.EmbedMessage(gl.MsgCantFindSearch)
+	if !found {
+		return ctx.UserError(gl.MsgCantFindSearch)
 	}
 
-	if trackIdx == 0 {
+	if arg == "cancel" {
 		// Cancel selection silently
 		ms.Searches.Remove(key)
 		ms.us.Session.ChannelMessageDelete(i.ChannelID, i.Message.ID)
 		return nil
 	}
 
-	track := &ps.Results[trackIdx-1]
-	r, _, vc := ms.us.GetVoiceChannelID(i.Member, i.GuildID, i.Member.User.ID)
-	if r != "" {
-		return ms.us.EmbedMessage(r)
+	ps.Selected = ps.Selected[:0]
+	for _, value := range i.MessageComponentData().Values {
+		trackIdx, err := strconv.Atoi(value)
+		if err != nil || trackIdx < 1 || trackIdx > len(ps.Results) {
+			return ctx.UserError(gl.MsgInvalidTrackNumber)
+		}
+		ps.Selected = append(ps.Selected, trackIdx-1)
+	}
+	if len(ps.Selected) == 0 {
+		return ctx.UserError(gl.MsgInvalidTrackNumber)
 	}
+	slices.Sort(ps.Selected)
 
-	voice, err := ms.GetVoiceConnection(vc, i.GuildID)
-	if err != nil {
-		return ms.us.EmbedMessage(err.Error())
+	q, r := ms.joinQueue(ctx)
+	if r != nil {
+		return r
 	}
 
-	q, err := ms.GetOrCreateQueue(voice, vc)
-	if err != nil {
-		ms.Logger.Error("could not create queue", "error", err)
-		voice.Disconnect(ms.us.Ctx)
-		return ms.us.EmbedMessage(gl.MsgError)
+	tracks := make([]miri.SongResult, 0, len(ps.Selected))
+	for _, idx := range ps.Selected {
+		tracks = append(tracks, ps.Results[idx])
 	}
 
-	q.AddTrack(ms, track)
+	queued := q.InsertTracks(ms, tracks, ctx.Author.ID, 0)
 	ms.Searches.Remove(key)
 	defer ms.us.Session.ChannelMessageDelete(i.ChannelID, i.Message.ID)
 
-	return ms.us.EmbedTrackMessage(track)
+	if len(tracks) == 1 {
+		return ms.us.ReplyMessage(ms.trackMessage(ctx.Locale(), &tracks[0], q, queued))
+	}
+
+	out := ctx.T(gl.MsgAddedTracksFmt, len(tracks)) + "\n"
+	for n, track := range tracks {
+		out += fmt.Sprintf(gl.MsgOrderedList, n+1, ms.us.FormatTrackLine(&track))
+	}
+	return ms.us.Reply(out)
+}
+
+// HandlePlayTrack enqueues the track whose ID is arg, from a "Play this"
+// button, or the one picked from a select menu, whose values are numbered IDs.
+func (ms *MusicService) HandlePlayTrack(arg string, ctx *gl.CommandContext) *gl.CommandResult {
+	i := ctx.Interaction
+	if ctx.Member == nil {
+		return ctx.UserError(gl.MsgUseInServer)
+	}
+
+	if values := i.MessageComponentData().Values; len(values) > 0 {
+		_, arg, _ = strings.Cut(values[0], ":")
+	}
+
+	track := ms.ResolveTracks([]string{arg})[0]
+	if track == nil {
+		return ctx.UserError(gl.MsgTrackGone)
+	}
+
+	q, r := ms.joinQueue(ctx)
+	if r != nil {
+		return r
+	}
+
+	queued := q.InsertTracks(ms, []miri.SongResult{*track}, ctx.Author.ID, 0)
+	return ms.us.ReplyMessage(ms.trackMessage(ctx.Locale(), track, q, queued))
+}
+
+func (ms *MusicService) HandleQueueShuffle(ctx *gl.CommandContext) *gl.CommandResult {
+	q, r := ms.controlledQueue(ctx.Member, ctx.GuildID, ctx.Author.ID)
+	if r != "" {
+		return ctx.UserError(r)
+	}
+
+	q.Shuffle()
+	return ctx.Reply(gl.MsgShuffled)
+}
+
+func (ms *MusicService) HandleQueueMove(ctx *gl.CommandContext) *gl.CommandResult {
+	q, r := ms.controlledQueue(ctx.Member, ctx.GuildID, ctx.Author.ID)
+	if r != "" {
+		return ctx.UserError(r)
+	}
+
+	from, _ := ctx.Options.Int("from")
+	to, _ := ctx.Options.Int("to")
+	track, ok := q.Move(int(from), int(to))
+	if !ok {
+		return ctx.UserError(gl.MsgInvalidTrackNumber)
+	}
+
+	return ctx.Reply(gl.MsgMovedFmt, ms.us.FormatTrackLine(&track), to)
 }
@@ -3,6 +3,7 @@ package music
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"io"
 	"math"
 	"os/exec"
@@ -96,23 +97,22 @@ func newAudioFromReader(input io.Reader, vc *discordgo.VoiceConnection, ms *Musi
 	return a, nil
 }
 
-func (ms *MusicService) HandleDebugSound(args string, m *discordgo.MessageCreate) *discordgo.MessageSend {
-	r, _, vc := ms.us.GetVoiceChannelID(m.Member, m.GuildID, m.Author.ID)
+func (ms *MusicService) HandleDebugSound(ctx *gl.CommandContext) *gl.CommandResult {
+	r, _, vc := ms.us.GetVoiceChannelID(ctx.Member, ctx.GuildID, ctx.Author.ID)
 	if r != "" {
-		return ms.us.EmbedMessage(r)
+		return ctx.UserError(r)
 	}
 
-	voice, err := ms.GetVoiceConnection(vc, m.GuildID)
+	voice, err := ms.GetVoiceConnection(vc, ctx.GuildID)
 	if err != nil {
-		return ms.us.EmbedMessage(gl.MsgError)
+		return ctx.InternalError(err)
 	}
 
 	wav := generateWAV(440.0, 3.0, gl.AudioFrameRate, gl.AudioChannels)
 
 	a, err := newAudioFromReader(bytes.NewReader(wav), voice, ms)
 	if err != nil {
-		ms.Logger.Error("could not create debug audio", "error", err)
-		return ms.us.EmbedMessage(gl.MsgError)
+		return ctx.InternalError(errors.New("could not create debug audio: " + err.Error()))
 	}
 
 	a.onFinish = func() {
@@ -120,5 +120,5 @@ func (ms *MusicService) HandleDebugSound(args string, m *discordgo.MessageCreate
 	}
 	a.Monitor()
 
-	return ms.us.EmbedMessage("Playing debug tone (440Hz, 3s).")
+	return ms.us.Reply("Playing debug tone (440Hz, 3s).")
 }
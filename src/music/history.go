@@ -0,0 +1,247 @@
+package music
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	historyLimit = 15
+	topLimit     = 10
+
+	historyPruneInterval = 24 * time.Hour
+)
+
+// PlayRecord is an entry of the listening history, written when a track stops playing.
+type PlayRecord struct {
+	GuildID   string    `json:"guild_id"`
+	Requester string    `json:"requester,omitempty"`
+	TrackID   string    `json:"track_id"`
+	Title     string    `json:"title"`
+	ArtistID  string    `json:"artist_id"`
+	Artist    string    `json:"artist"`
+	Started   time.Time `json:"started"`
+	Skipped   bool      `json:"skipped"`
+	Listened  int       `json:"listened"` // seconds
+}
+
+// StatEntry is a row of a top chart.
+type StatEntry struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Plays   int    `json:"plays"`
+	Seconds int    `json:"seconds"`
+}
+
+// StatsPeriods are the time windows offered by the history and top commands.
+var StatsPeriods = []string{"day", "week", "month", "year", "all"}
+
+// PeriodStart returns when the named period began, or the zero time for "all".
+func PeriodStart(period string, now time.Time) time.Time {
+	switch period {
+	case "day":
+		return now.AddDate(0, 0, -1)
+	case "week", "":
+		return now.AddDate(0, 0, -7)
+	case "month":
+		return now.AddDate(0, -1, 0)
+	case "year":
+		return now.AddDate(-1, 0, 0)
+	}
+	return time.Time{}
+}
+
+// recordPlay adds the current track of q to the listening history.
+func (ms *MusicService) recordPlay(guildID string, q *Queue, skipped bool) {
+	np := q.nowPlaying
+	if np == nil {
+		return
+	}
+
+	var listened int
+	if q.audioStream != nil {
+		listened = int(q.audioStream.Position().Seconds())
+	}
+
+	err := ms.history.Append(PlayRecord{
+		GuildID:   guildID,
+		Requester: q.requester,
+		TrackID:   fmt.Sprint(np.ID),
+		Title:     np.Title,
+		ArtistID:  fmt.Sprint(np.Artist.ID),
+		Artist:    np.Artist.Name,
+		Started:   q.started,
+		Skipped:   skipped,
+		Listened:  listened,
+	})
+	if err != nil {
+		ms.Logger.Error("could not record play", "error", err)
+	}
+}
+
+// pruneHistory drops the plays older than the configured retention, right away
+// and then once a day, so that reading the history stays cheap.
+func (ms *MusicService) pruneHistory() {
+	days := int(ms.us.Config.HistoryRetention)
+	if days == 0 {
+		return
+	}
+
+	for {
+		cutoff := time.Now().AddDate(0, 0, -days)
+		dropped, err := ms.history.Retain(func(r PlayRecord) bool { return !r.Started.Before(cutoff) })
+		if err != nil {
+			ms.Logger.Error("could not prune history", "error", err)
+		} else if dropped > 0 {
+			ms.Logger.Debug("Pruned history", "dropped", dropped)
+		}
+
+		select {
+		case <-ms.us.Ctx.Done():
+			return
+		case <-time.After(historyPruneInterval):
+		}
+	}
+}
+
+// History returns the plays of guildID since the given time, most recent first.
+func (ms *MusicService) History(guildID string, since time.Time, limit int) []PlayRecord {
+	records := ms.playsSince(guildID, since)
+	slices.Reverse(records)
+	return records[:min(len(records), limit)]
+}
+
+// TopTracks, TopArtists and TopListeners rank the plays of guildID since the given time.
+func (ms *MusicService) TopTracks(guildID string, since time.Time, limit int) []StatEntry {
+	return topEntries(ms.playsSince(guildID, since), limit, func(r PlayRecord) (string, string) {
+		return r.TrackID, r.Artist + " - " + r.Title
+	})
+}
+
+func (ms *MusicService) TopArtists(guildID string, since time.Time, limit int) []StatEntry {
+	return topEntries(ms.playsSince(guildID, since), limit, func(r PlayRecord) (string, string) {
+		return r.ArtistID, r.Artist
+	})
+}
+
+func (ms *MusicService) TopListeners(guildID string, since time.Time, limit int) []StatEntry {
+	return topEntries(ms.playsSince(guildID, since), limit, func(r PlayRecord) (string, string) {
+		return r.Requester, r.Requester
+	})
+}
+
+func (ms *MusicService) playsSince(guildID string, since time.Time) []PlayRecord {
+	var records []PlayRecord
+	err := ms.history.Each(func(r PlayRecord) bool {
+		if r.GuildID == guildID && !r.Started.Before(since) {
+			records = append(records, r)
+		}
+		return true
+	})
+	if err != nil {
+		ms.Logger.Error("could not read history", "error", err)
+	}
+	return records
+}
+
+// topEntries counts plays by the key returned by group, ignoring empty keys,
+// and returns the most played first.
+func topEntries(records []PlayRecord, limit int, group func(PlayRecord) (id, name string)) []StatEntry {
+	byID := map[string]*StatEntry{}
+	for _, r := range records {
+		id, name := group(r)
+		if id == "" {
+			continue
+		}
+
+		e, ok := byID[id]
+		if !ok {
+			e = &StatEntry{ID: id, Name: name}
+			byID[id] = e
+		}
+		e.Plays++
+		e.Seconds += r.Listened
+	}
+
+	entries := make([]StatEntry, 0, len(byID))
+	for _, e := range byID {
+		entries = append(entries, *e)
+	}
+	slices.SortFunc(entries, func(a, b StatEntry) int {
+		if c := cmp.Compare(b.Plays, a.Plays); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Seconds, a.Seconds); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return entries[:min(len(entries), limit)]
+}
+
+// HandleHistory lists the latest tracks played in this server.
+func (ms *MusicService) HandleHistory(ctx *gl.CommandContext) *gl.CommandResult {
+	if ctx.GuildID == "" {
+		return ctx.UserError(gl.MsgUseInServer)
+	}
+
+	records := ms.History(ctx.GuildID, PeriodStart(ctx.Options.String("period"), time.Now()), historyLimit)
+	if len(records) == 0 {
+		return ctx.Reply(gl.MsgNoHistory)
+	}
+
+	var out string
+	for _, r := range records {
+		line := ctx.T(gl.MsgHistoryLineFmt, r.Started.Unix(), r.Artist, r.Title)
+		if r.Requester != "" {
+			line += ctx.T(gl.MsgRequestedByFmt, r.Requester)
+		}
+		if r.Skipped {
+			line += ctx.T(gl.MsgHistorySkipped)
+		}
+		out += fmt.Sprintf(gl.MsgUnorderedList, line)
+	}
+
+	response := ms.us.EmbedMessage(out)
+	response.AllowedMentions = &discordgo.MessageAllowedMentions{}
+	return ms.us.ReplyMessage(response)
+}
+
+// HandleTopTracks, HandleTopArtists and HandleTopListeners show the charts of this server.
+func (ms *MusicService) HandleTopTracks(ctx *gl.CommandContext) *gl.CommandResult {
+	return ms.topResult(ms.TopTracks, "**%s**", ctx.Options, ctx)
+}
+
+func (ms *MusicService) HandleTopArtists(ctx *gl.CommandContext) *gl.CommandResult {
+	return ms.topResult(ms.TopArtists, "**%s**", ctx.Options, ctx)
+}
+
+func (ms *MusicService) HandleTopListeners(ctx *gl.CommandContext) *gl.CommandResult {
+	return ms.topResult(ms.TopListeners, "<@%s>", ctx.Options, ctx)
+}
+
+func (ms *MusicService) topResult(top func(string, time.Time, int) []StatEntry, nameFmt string, opts gl.CommandOptions, ctx *gl.CommandContext) *gl.CommandResult {
+	if ctx.GuildID == "" {
+		return ctx.UserError(gl.MsgUseInServer)
+	}
+
+	entries := top(ctx.GuildID, PeriodStart(opts.String("period"), time.Now()), topLimit)
+	if len(entries) == 0 {
+		return ms.us.Reply(ctx.T(gl.MsgNoHistory))
+	}
+
+	var out string
+	for n, e := range entries {
+		name := fmt.Sprintf(nameFmt, e.Name)
+		out += fmt.Sprintf(gl.MsgOrderedList, n+1, ctx.T(gl.MsgTopLineFmt, name, e.Plays))
+	}
+
+	response := ms.us.EmbedMessage(out)
+	response.AllowedMentions = &discordgo.MessageAllowedMentions{}
+	return ms.us.ReplyMessage(response)
+}
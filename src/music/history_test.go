@@ -0,0 +1,29 @@
+package music
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopEntries(t *testing.T) {
+	records := []PlayRecord{
+		{TrackID: "1", Title: "One", Artist: "A", Requester: "u1", Listened: 100},
+		{TrackID: "2", Title: "Two", Artist: "B", Requester: "u2", Listened: 30},
+		{TrackID: "1", Title: "One", Artist: "A", Requester: "u2", Listened: 50},
+		{TrackID: "3", Title: "Three", Artist: "C", Listened: 200},
+	}
+
+	got := topEntries(records, 2, func(r PlayRecord) (string, string) { return r.TrackID, r.Title })
+	want := []StatEntry{
+		{ID: "1", Name: "One", Plays: 2, Seconds: 150},
+		{ID: "3", Name: "Three", Plays: 1, Seconds: 200},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("topEntries = %+v, want %+v", got, want)
+	}
+
+	listeners := topEntries(records, 10, func(r PlayRecord) (string, string) { return r.Requester, r.Requester })
+	if len(listeners) != 2 || listeners[0].ID != "u2" {
+		t.Errorf("unknown requesters should be ignored, got %+v", listeners)
+	}
+}
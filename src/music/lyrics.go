@@ -0,0 +1,330 @@
+package music
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/birabittoh/miri"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	lyricsInteraction  = "lyrics"
+	lyricsLiveInterval = time.Second
+	lyricsLiveBefore   = 2 // lines shown before the current one
+	lyricsLiveAfter    = 4 // lines shown after the current one
+)
+
+var lrcTimestamp = regexp.MustCompile(`^\[(\d+):(\d{2})(?:[.:](\d{1,3}))?\]`)
+
+// lyricLine is a line of synced lyrics along with when it starts.
+type lyricLine struct {
+	At   time.Duration
+	Text string
+}
+
+// liveLyrics is a message following the lyrics of whatever is playing.
+type liveLyrics struct {
+	channelID string
+	messageID string
+	locale    string
+	trackID   string
+	lines     []lyricLine
+	current   int
+	cancel    context.CancelFunc
+}
+
+// parseLRC reads the timed lines of LRC lyrics in order of time. Lines may
+// carry more than one timestamp. It returns nil for plain lyrics.
+func parseLRC(lyrics string) []lyricLine {
+	var lines []lyricLine
+	for _, raw := range strings.Split(lyrics, "\n") {
+		raw = strings.TrimSpace(raw)
+
+		var stamps []time.Duration
+		for {
+			match := lrcTimestamp.FindStringSubmatch(raw)
+			if match == nil {
+				break
+			}
+			minutes, _ := strconv.Atoi(match[1])
+			seconds, _ := strconv.Atoi(match[2])
+			millis, _ := strconv.Atoi((match[3] + "000")[:3])
+			stamps = append(stamps, time.Duration(minutes)*time.Minute+time.Duration(seconds)*time.Second+time.Duration(millis)*time.Millisecond)
+			raw = raw[len(match[0]):]
+		}
+
+		for _, at := range stamps {
+			lines = append(lines, lyricLine{At: at, Text: strings.TrimSpace(raw)})
+		}
+	}
+
+	slices.SortStableFunc(lines, func(a, b lyricLine) int { return cmp.Compare(a.At, b.At) })
+	return lines
+}
+
+// plainLyrics strips the timestamps and tags of LRC lyrics, leaving plain lyrics untouched.
+func plainLyrics(lyrics string) string {
+	lines := parseLRC(lyrics)
+	if lines == nil {
+		return strings.TrimSpace(lyrics)
+	}
+
+	text := make([]string, 0, len(lines))
+	for _, l := range lines {
+		text = append(text, l.Text)
+	}
+	return strings.TrimSpace(strings.Join(text, "\n"))
+}
+
+// currentLine returns the index of the line being sung at pos, or -1 before the first one.
+func currentLine(lines []lyricLine, pos time.Duration) int {
+	n, _ := slices.BinarySearchFunc(lines, pos, func(l lyricLine, pos time.Duration) int {
+		if l.At <= pos {
+			return -1
+		}
+		return 1
+	})
+	return n - 1
+}
+
+// paginate splits text into pages of at most limit runes, breaking between
+// lines unless a single line is longer than a page.
+func paginate(text string, limit int) []string {
+	var pages []string
+	var page []rune
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		if len(page) > 0 && len(page)+1+len(runes) > limit {
+			pages = append(pages, string(page))
+			page = nil
+		}
+		if len(page) > 0 {
+			page = append(page, '\n')
+		}
+		for len(runes) > limit {
+			pages = append(pages, string(runes[:limit]))
+			runes = runes[limit:]
+		}
+		page = append(page, runes...)
+	}
+	if len(page) > 0 || len(pages) == 0 {
+		pages = append(pages, string(page))
+	}
+	return pages
+}
+
+// lyrics fetches the lyrics of track, as LRC when it has synced lyrics.
+func (ms *MusicService) lyrics(track *miri.SongResult) (string, error) {
+	id := fmt.Sprint(track.ID)
+	if lyrics, ok := ms.lyricsCache.Get(id); ok {
+		return lyrics, nil
+	}
+
+	lyrics, err := track.Lyrics(ms.us.Ctx)
+	if err != nil {
+		return "", err
+	}
+	ms.lyricsCache.Add(id, lyrics)
+	return lyrics, nil
+}
+
+func (ms *MusicService) lyricsResult(track *miri.SongResult, ctx *gl.CommandContext) *gl.CommandResult {
+	lyrics, err := ms.lyrics(track)
+	if err != nil || lyrics == "" {
+		ms.Logger.Error("could not fetch lyrics", "error", err)
+		return ctx.UserError(gl.MsgNoLyrics)
+	}
+
+	// the buttons look the track up again by ID
+	ms.tracks.Add(fmt.Sprint(track.ID), *track)
+	return ms.us.ReplyMessage(ms.lyricsPage(ctx.Locale(), track, paginate(plainLyrics(lyrics), gl.DiscordEmbedDescriptionLimit), 0))
+}
+
+// lyricsPage shows one page of lyrics in locale with a button to play the
+// song, and buttons to turn pages when there are more.
+func (ms *MusicService) lyricsPage(locale string, track *miri.SongResult, pages []string, page int) *discordgo.MessageSend {
+	id := fmt.Sprint(track.ID)
+	response := ms.us.EmbedTrackMessage(locale, track)
+	response.Embeds[0].Description = pages[page]
+
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{Label: ms.us.Translator.Message(locale, gl.MsgPlayThis), Style: discordgo.PrimaryButton, CustomID: playTrackInteraction + ":" + id},
+	}
+	if len(pages) > 1 {
+		response.Embeds[0].Footer = &discordgo.MessageEmbedFooter{Text: ms.us.Translator.Sprintf(locale, gl.MsgPageFmt, page+1, len(pages))}
+		pageButton := func(label string, to int) discordgo.Button {
+			return discordgo.Button{
+				Label:    label,
+				Style:    discordgo.SecondaryButton,
+				CustomID: fmt.Sprintf("%s:%s:%d", lyricsInteraction, id, to),
+				Disabled: to < 0 || to >= len(pages),
+			}
+		}
+		buttons = append(buttons, pageButton("◀", page-1), pageButton("▶", page+1))
+	}
+
+	response.Components = []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+	return response
+}
+
+// HandleLyricsPage turns the page of a lyrics message; arg is the track ID and the page.
+func (ms *MusicService) HandleLyricsPage(arg string, ctx *gl.CommandContext) *gl.CommandResult {
+	i := ctx.Interaction
+	id, rawPage, _ := strings.Cut(arg, ":")
+	page, err := strconv.Atoi(rawPage)
+	if err != nil {
+		return ctx.UserError(gl.MsgUnknownCommand, arg)
+	}
+
+	// fetching the lyrics may take longer than Discord waits for an answer
+	ms.us.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	track := ms.ResolveTracks([]string{id})[0]
+	if track == nil {
+		return nil
+	}
+	lyrics, err := ms.lyrics(track)
+	if err != nil || lyrics == "" {
+		ms.Logger.Error("could not fetch lyrics", "error", err)
+		return nil
+	}
+
+	pages := paginate(plainLyrics(lyrics), gl.DiscordEmbedDescriptionLimit)
+	msg := ms.lyricsPage(ctx.Locale(), track, pages, max(0, min(page, len(pages)-1)))
+	if _, err := ms.us.Session.InteractionResponseEdit(i.Interaction, ms.us.EmbedToWebhookEdit(msg)); err != nil {
+		ms.Logger.Error("could not turn lyrics page", "error", err)
+	}
+	return nil
+}
+
+// HandleLyricsLive replies with a message that follows the synced lyrics of
+// the current song, and of the next ones until the queue stops.
+func (ms *MusicService) HandleLyricsLive(ctx *gl.CommandContext) *gl.CommandResult {
+	q := ms.GetQueue(ctx.GuildID)
+	if q == nil || q.nowPlaying == nil {
+		return ctx.UserError(gl.MsgNothingIsPlaying)
+	}
+
+	ll := &liveLyrics{locale: ctx.Locale()}
+	msg, ok := ms.liveLyricsMessage(q, ll)
+	if !ok {
+		return ctx.UserError(gl.MsgNoSyncedLyrics)
+	}
+
+	r := ms.us.ReplyMessage(msg)
+	r.Sent = func(sent *discordgo.Message) { ms.startLiveLyrics(q, ll, sent) }
+	return r
+}
+
+// startLiveLyrics keeps editing sent, replacing the live lyrics the queue was
+// following until now.
+func (ms *MusicService) startLiveLyrics(q *Queue, ll *liveLyrics, sent *discordgo.Message) {
+	ll.channelID, ll.messageID = sent.ChannelID, sent.ID
+	lyricsCtx, cancel := context.WithCancel(ms.us.Ctx)
+	ll.cancel = cancel
+
+	q.npMu.Lock()
+	if q.lyrics != nil {
+		q.lyrics.cancel()
+	}
+	q.lyrics = ll
+	q.npMu.Unlock()
+
+	go ms.followLyrics(lyricsCtx, q, ll)
+}
+
+// followLyrics edits the live lyrics message whenever the current line
+// changes, until ctx is cancelled by a newer one or by the queue stopping.
+func (ms *MusicService) followLyrics(ctx context.Context, q *Queue, ll *liveLyrics) {
+	ticker := time.NewTicker(lyricsLiveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := ll.current
+		trackID := ll.trackID
+		msg, _ := ms.liveLyricsMessage(q, ll)
+		if msg == nil || (ll.current == current && ll.trackID == trackID) {
+			continue
+		}
+		if !ms.editLiveLyrics(ctx, q, ll, msg) {
+			return
+		}
+	}
+}
+
+// editLiveLyrics shows msg unless ctx was cancelled in the meantime, so that
+// it never overwrites the message closing the queue. It reports whether the
+// message can still be edited.
+func (ms *MusicService) editLiveLyrics(ctx context.Context, q *Queue, ll *liveLyrics, msg *discordgo.MessageSend) bool {
+	q.npMu.Lock()
+	defer q.npMu.Unlock()
+
+	if ctx.Err() != nil {
+		return false
+	}
+	edit := discordgo.NewMessageEdit(ll.channelID, ll.messageID).SetEmbeds(msg.Embeds)
+	if _, err := ms.us.Session.ChannelMessageEditComplex(edit); err != nil {
+		ms.Logger.Debug("could not edit live lyrics, stopping", "error", err)
+		return false
+	}
+	return true
+}
+
+// liveLyricsMessage shows the lines around the one being sung, loading the
+// lyrics when the song changed. It reports false when they are not synced.
+func (ms *MusicService) liveLyricsMessage(q *Queue, ll *liveLyrics) (*discordgo.MessageSend, bool) {
+	np := q.nowPlaying
+	if np == nil {
+		return nil, false
+	}
+
+	if id := fmt.Sprint(np.ID); id != ll.trackID {
+		ll.trackID, ll.lines, ll.current = id, nil, -1
+		lyrics, err := ms.lyrics(np)
+		if err != nil {
+			ms.Logger.Debug("could not fetch lyrics", "error", err)
+		}
+		ll.lines = parseLRC(lyrics)
+	}
+
+	response := ms.us.EmbedTrackMessage(ll.locale, np)
+	response.Embeds[0].Footer = &discordgo.MessageEmbedFooter{Text: ms.us.Translator.Message(ll.locale, gl.MsgLyricsLive)}
+	if len(ll.lines) == 0 {
+		response.Embeds[0].Description = ms.us.Translator.Message(ll.locale, gl.MsgNoSyncedLyrics)
+		return response, false
+	}
+
+	if stream := q.AudioStream(); stream != nil {
+		ll.current = currentLine(ll.lines, stream.Position())
+	}
+
+	var out []string
+	for n := max(0, ll.current-lyricsLiveBefore); n < min(len(ll.lines), ll.current+lyricsLiveAfter+1); n++ {
+		text := ll.lines[n].Text
+		if text == "" {
+			text = "♪"
+		}
+		if n == ll.current {
+			text = "**" + text + "**"
+		}
+		out = append(out, text)
+	}
+	response.Embeds[0].Description = strings.Join(out, "\n")
+	return response, true
+}
@@ -0,0 +1,57 @@
+package music
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLRC(t *testing.T) {
+	lyrics := "[ar:Someone]\n[00:12.50]First line\n[00:05.00][00:20.123]Chorus\n[01:02]\nnot timed"
+
+	lines := parseLRC(lyrics)
+	want := []lyricLine{
+		{5 * time.Second, "Chorus"},
+		{12500 * time.Millisecond, "First line"},
+		{20123 * time.Millisecond, "Chorus"},
+		{62 * time.Second, ""},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %v, want %v", lines, want)
+	}
+	for n := range want {
+		if lines[n] != want[n] {
+			t.Errorf("line %d = %v, want %v", n, lines[n], want[n])
+		}
+	}
+
+	if parseLRC("just\nplain lyrics") != nil {
+		t.Error("plain lyrics should not be synced")
+	}
+
+	for pos, wantLine := range map[time.Duration]int{0: -1, 5 * time.Second: 0, 15 * time.Second: 1, time.Hour: 3} {
+		if got := currentLine(lines, pos); got != wantLine {
+			t.Errorf("currentLine(%v) = %d, want %d", pos, got, wantLine)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	cases := []struct {
+		text  string
+		limit int
+		want  []string
+	}{
+		{"", 10, []string{""}},
+		{"one\ntwo", 10, []string{"one\ntwo"}},
+		{"one\ntwo\nthree", 8, []string{"one\ntwo", "three"}},
+		{"short\n" + strings.Repeat("x", 12), 5, []string{"short", "xxxxx", "xxxxx", "xx"}},
+	}
+
+	for _, tc := range cases {
+		got := paginate(tc.text, tc.limit)
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("paginate(%q, %d) = %q, want %q", tc.text, tc.limit, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package music
+
+import (
+	"strings"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/bwmarrin/discordgo"
+)
+
+const nowPlayingInteraction = "now_playing"
+
+func nowPlayingButton(label, action string, style discordgo.ButtonStyle) discordgo.Button {
+	return discordgo.Button{
+		Label:    label,
+		Style:    style,
+		CustomID: nowPlayingInteraction + ":" + action,
+	}
+}
+
+// nowPlayingMessage builds the now-playing embed for q along with its playback
+// controls. It is shown to everyone, so it uses the language of the server.
+func (ms *MusicService) nowPlayingMessage(q *Queue) *discordgo.MessageSend {
+	locale := ms.us.GuildLocale(q.guildID)
+	np := q.nowPlaying
+	if np == nil {
+		return ms.us.EmbedMessage(ms.us.Translator.Message(locale, gl.MsgNothingIsPlaying))
+	}
+
+	response := ms.trackMessage(locale, np, q, 0)
+	response.Embeds[0].Author = &discordgo.MessageEmbedAuthor{Name: ms.us.Translator.Message(locale, gl.MsgNowPlaying)}
+
+	status := []string{ms.us.Translator.Sprintf(locale, gl.MsgUpNextFmt, len(q.items))}
+	if q.Paused() {
+		status = append(status, ms.us.Translator.Message(locale, gl.MsgPaused))
+	}
+	if q.Loop() {
+		status = append(status, ms.us.Translator.Message(locale, gl.MsgLoopOn))
+	}
+	response.Embeds[0].Footer = &discordgo.MessageEmbedFooter{Text: strings.Join(status, " • ")}
+
+	label := func(key string) string { return ms.us.Translator.Message(locale, key) }
+	pause := nowPlayingButton(label(gl.MsgButtonPause), "pause", discordgo.SecondaryButton)
+	if q.Paused() {
+		pause = nowPlayingButton(label(gl.MsgButtonResume), "pause", discordgo.PrimaryButton)
+	}
+	loop := nowPlayingButton(label(gl.MsgButtonLoop), "loop", discordgo.SecondaryButton)
+	if q.Loop() {
+		loop.Style = discordgo.SuccessButton
+	}
+
+	response.Components = []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				pause,
+				nowPlayingButton(label(gl.MsgButtonSkip), "skip", discordgo.SecondaryButton),
+				loop,
+				nowPlayingButton(label(gl.MsgButtonShuffle), "shuffle", discordgo.SecondaryButton),
+				nowPlayingButton(label(gl.MsgButtonStop), "stop", discordgo.DangerButton),
+			},
+		},
+	}
+	return response
+}
+
+// UpdateNowPlaying posts the now-playing message for q, or edits the previous one in place.
+func (ms *MusicService) UpdateNowPlaying(q *Queue) {
+	q.npMu.Lock()
+	defer q.npMu.Unlock()
+
+	if q.textChannel == "" {
+		return
+	}
+
+	msg := ms.nowPlayingMessage(q)
+	if q.npMessageID != "" {
+		edit := discordgo.NewMessageEdit(q.textChannel, q.npMessageID).SetEmbeds(msg.Embeds)
+		edit.Components = &msg.Components
+		_, err := ms.us.Session.ChannelMessageEditComplex(edit)
+		if err == nil {
+			return
+		}
+		ms.Logger.Debug("could not edit now playing message, sending a new one", "error", err)
+	}
+
+	sent, err := ms.us.Session.ChannelMessageSendComplex(q.textChannel, msg)
+	if err != nil {
+		ms.Logger.Error("could not send now playing message", "error", err)
+		return
+	}
+	q.npMessageID = sent.ID
+}
+
+// setNowPlayingChannel moves the now-playing message to channelID, removing the old one.
+func (ms *MusicService) setNowPlayingChannel(q *Queue, channelID string) {
+	q.npMu.Lock()
+	defer q.npMu.Unlock()
+
+	if channelID == "" || channelID == q.textChannel {
+		return
+	}
+
+	if q.npMessageID != "" {
+		ms.us.Session.ChannelMessageDelete(q.textChannel, q.npMessageID)
+		q.npMessageID = ""
+	}
+	q.SetTextChannel(channelID)
+}
+
+// closeNowPlaying strips the controls from the last now-playing message once
+// the queue is gone, and stops following its lyrics.
+func (ms *MusicService) closeNowPlaying(q *Queue) {
+	q.npMu.Lock()
+	defer q.npMu.Unlock()
+
+	if ll := q.lyrics; ll != nil {
+		ll.cancel()
+		edit := discordgo.NewMessageEdit(ll.channelID, ll.messageID).SetEmbeds(ms.us.EmbedMessage(ms.us.Translator.Message(ll.locale, gl.MsgLeft)).Embeds)
+		if _, err := ms.us.Session.ChannelMessageEditComplex(edit); err != nil {
+			ms.Logger.Debug("could not close live lyrics message", "error", err)
+		}
+		q.lyrics = nil
+	}
+
+	if q.npMessageID == "" {
+		return
+	}
+
+	edit := discordgo.NewMessageEdit(q.textChannel, q.npMessageID).SetEmbeds(ms.us.EmbedMessage(ms.us.TGuild(q.guildID, gl.MsgLeft)).Embeds)
+	edit.Components = &[]discordgo.MessageComponent{}
+	if _, err := ms.us.Session.ChannelMessageEditComplex(edit); err != nil {
+		ms.Logger.Debug("could not close now playing message", "error", err)
+	}
+	q.npMessageID = ""
+}
+
+func (ms *MusicService) HandleNowPlayingControl(arg string, ctx *gl.CommandContext) *gl.CommandResult {
+	i := ctx.Interaction
+	r, g, vc := ms.us.GetVoiceChannelID(ctx.Member, ctx.GuildID, ctx.Author.ID)
+	if r != "" {
+		return ctx.UserError(r)
+	}
+
+	q := ms.GetQueue(g.ID)
+	if q == nil {
+		return ctx.UserError(gl.MsgNothingIsPlaying)
+	}
+
+	if vc != q.VoiceChannelID() {
+		return ctx.UserError(gl.MsgSameVoiceChannel)
+	}
+
+	switch arg {
+	case "pause":
+		q.TogglePause()
+	case "loop":
+		q.ToggleLoop()
+	case "shuffle":
+		q.Shuffle()
+	case "skip":
+		// the next track edits the message on its own
+		q.PlayNext(ms, true)
+		ms.us.Session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredMessageUpdate,
+		})
+		return nil
+	case "stop":
+		ms.DeleteQueue(g.ID)
+		msg := ms.us.EmbedMessage(ms.us.TGuild(g.ID, gl.MsgLeft))
+		msg.Components = []discordgo.MessageComponent{}
+		ms.respondUpdate(i, msg)
+		return nil
+	default:
+		return ctx.UserError(gl.MsgUnknownCommand, arg)
+	}
+
+	ms.respondUpdate(i, ms.nowPlayingMessage(q))
+	return nil
+}
+
+// respondUpdate answers a component interaction by editing the message it was attached to.
+func (ms *MusicService) respondUpdate(i *discordgo.InteractionCreate, msg *discordgo.MessageSend) {
+	response := ms.us.EmbedToResponse(msg)
+	response.Type = discordgo.InteractionResponseUpdateMessage
+	if err := ms.us.Session.InteractionRespond(i.Interaction, response); err != nil {
+		ms.Logger.Error("could not update now playing message", "error", err)
+	}
+}
@@ -0,0 +1,443 @@
+package music
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/birabittoh/miri"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Playlist file formats supported by import and export.
+const (
+	FormatM3U  = "m3u8"
+	FormatXSPF = "xspf"
+	FormatJSON = "json"
+
+	// maxImportSize and maxImportEntries bound what a single import may fetch and resolve.
+	maxImportSize    = 1 << 20
+	maxImportEntries = 200
+)
+
+// ErrImportTooLarge is returned for uploaded files larger than maxImportSize.
+var ErrImportTooLarge = errors.New("playlist file too large")
+
+var trackLinkRegex = regexp.MustCompile(`deezer\.com/(?:[a-z]{2}(?:-[a-z]{2})?/)?track/(\d+)`)
+
+// fileEntry is a track as described by a playlist file.
+type fileEntry struct {
+	ID       string `json:"id,omitempty"`
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	Album    string `json:"album,omitempty"`
+	Duration int    `json:"duration,omitempty"` // seconds
+}
+
+func entryFromTrack(track miri.SongResult) fileEntry {
+	return fileEntry{
+		ID:       fmt.Sprint(track.ID),
+		Title:    track.Title,
+		Artist:   track.Artist.Name,
+		Album:    track.Album.Title,
+		Duration: track.Duration,
+	}
+}
+
+func (e fileEntry) String() string {
+	if e.Artist == "" {
+		return e.Title
+	}
+	return e.Artist + " - " + e.Title
+}
+
+func trackLink(id string) string {
+	return "https://www.deezer.com/track/" + id
+}
+
+type jsonPlaylist struct {
+	Name   string      `json:"name"`
+	Tracks []fileEntry `json:"tracks"`
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location   string `xml:"location,omitempty"`
+	Identifier string `xml:"identifier,omitempty"`
+	Title      string `xml:"title,omitempty"`
+	Creator    string `xml:"creator,omitempty"`
+	Album      string `xml:"album,omitempty"`
+	Duration   int    `xml:"duration,omitempty"` // milliseconds
+}
+
+// encodePlaylist renders entries as a playlist file in the given format.
+func encodePlaylist(format, name string, entries []fileEntry) ([]byte, error) {
+	switch format {
+	case FormatM3U:
+		var b bytes.Buffer
+		b.WriteString("#EXTM3U\n")
+		if name != "" {
+			fmt.Fprintf(&b, "#PLAYLIST:%s\n", name)
+		}
+		for _, e := range entries {
+			fmt.Fprintf(&b, "#EXTINF:%d,%s\n%s\n", e.Duration, e.String(), trackLink(e.ID))
+		}
+		return b.Bytes(), nil
+
+	case FormatXSPF:
+		p := xspfPlaylist{Version: "1", Xmlns: "http://xspf.org/ns/0/", Title: name}
+		for _, e := range entries {
+			p.Tracks = append(p.Tracks, xspfTrack{
+				Location:   trackLink(e.ID),
+				Identifier: trackLink(e.ID),
+				Title:      e.Title,
+				Creator:    e.Artist,
+				Album:      e.Album,
+				Duration:   e.Duration * 1000,
+			})
+		}
+		data, err := xml.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(xml.Header), data...), nil
+
+	case FormatJSON:
+		return json.MarshalIndent(jsonPlaylist{Name: name, Tracks: entries}, "", "  ")
+	}
+	return nil, errors.New("unknown playlist format: " + format)
+}
+
+// detectFormat guesses the format of a playlist file from its name, then its content.
+func detectFormat(filename string, data []byte) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".m3u", ".m3u8":
+		return FormatM3U
+	case ".xspf":
+		return FormatXSPF
+	case ".json":
+		return FormatJSON
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")):
+		return FormatJSON
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return FormatXSPF
+	}
+	return FormatM3U
+}
+
+// decodePlaylist reads the entries of a playlist file, returning the playlist
+// name when the file has one.
+func decodePlaylist(format string, data []byte) (name string, entries []fileEntry, err error) {
+	switch format {
+	case FormatM3U:
+		entries = decodeM3U(data)
+		for _, line := range strings.Split(string(data), "\n") {
+			if after, ok := strings.CutPrefix(strings.TrimSpace(line), "#PLAYLIST:"); ok {
+				name = strings.TrimSpace(after)
+			}
+		}
+
+	case FormatXSPF:
+		var p xspfPlaylist
+		if err = xml.Unmarshal(data, &p); err != nil {
+			return "", nil, errors.New("could not read XSPF playlist: " + err.Error())
+		}
+		name = p.Title
+		for _, t := range p.Tracks {
+			e := fileEntry{Title: t.Title, Artist: t.Creator, Album: t.Album, Duration: t.Duration / 1000}
+			for _, link := range []string{t.Identifier, t.Location} {
+				if m := trackLinkRegex.FindStringSubmatch(link); m != nil {
+					e.ID = m[1]
+					break
+				}
+			}
+			if e.Title == "" && e.ID == "" {
+				e.Title = titleFromLocation(t.Location)
+			}
+			entries = append(entries, e)
+		}
+
+	case FormatJSON:
+		var p jsonPlaylist
+		if err = json.Unmarshal(data, &p); err != nil {
+			// also accept a bare list of tracks
+			if err = json.Unmarshal(data, &p.Tracks); err != nil {
+				return "", nil, errors.New("could not read JSON playlist: " + err.Error())
+			}
+		}
+		name, entries = p.Name, p.Tracks
+
+	default:
+		return "", nil, errors.New("unknown playlist format: " + format)
+	}
+
+	return name, entries, nil
+}
+
+// decodeM3U reads the entries of an M3U playlist, using #EXTINF titles when present.
+func decodeM3U(data []byte) []fileEntry {
+	var entries []fileEntry
+	var info string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// #EXTINF:<duration>,<artist> - <title>
+			info = strings.TrimPrefix(line, "#EXTINF:")
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		e := fileEntry{}
+		if m := trackLinkRegex.FindStringSubmatch(line); m != nil {
+			e.ID = m[1]
+		}
+
+		display := titleFromLocation(line)
+		if duration, title, ok := strings.Cut(info, ","); ok {
+			e.Duration, _ = strconv.Atoi(strings.TrimSpace(duration))
+			display = strings.TrimSpace(title)
+		}
+		e.Artist, e.Title, _ = strings.Cut(display, " - ")
+		if e.Title == "" {
+			e.Artist, e.Title = "", display
+		}
+
+		entries = append(entries, e)
+		info = ""
+	}
+	return entries
+}
+
+// titleFromLocation turns a file path such as "Music/Artist - Title.mp3" into "Artist - Title".
+func titleFromLocation(location string) string {
+	base := path.Base(strings.ReplaceAll(location, "\\", "/"))
+	return strings.TrimSpace(strings.TrimSuffix(base, path.Ext(base)))
+}
+
+// importStatus is the outcome of resolving a single playlist file entry.
+type importStatus int
+
+const (
+	importMatched importStatus = iota
+	importAmbiguous
+	importNotFound
+)
+
+// resolveEntry finds the track for a playlist file entry, by ID when it has one
+// and otherwise by searching its artist and title.
+func (ms *MusicService) resolveEntry(e fileEntry) (*miri.SongResult, importStatus) {
+	if e.ID != "" {
+		if track := ms.ResolveTracks([]string{e.ID})[0]; track != nil {
+			return track, importMatched
+		}
+	}
+	if e.Title == "" {
+		return nil, importNotFound
+	}
+
+	results, err := miri.SearchTracks(ms.us.Ctx, miri.SearchOptions{
+		Limit: 5,
+		Order: searchOrder,
+		Query: strings.TrimSpace(e.Artist + " " + e.Title),
+	})
+	if err != nil {
+		ms.Logger.Warn("could not search track", "entry", e.String(), "error", err)
+		return nil, importNotFound
+	}
+	if len(results) == 0 {
+		return nil, importNotFound
+	}
+
+	for _, r := range results {
+		if strings.EqualFold(r.Title, e.Title) && (e.Artist == "" || strings.EqualFold(r.Artist.Name, e.Artist)) {
+			return &r, importMatched
+		}
+	}
+	if len(results) == 1 {
+		return &results[0], importMatched
+	}
+	return &results[0], importAmbiguous
+}
+
+// fetchAttachment downloads an uploaded file, refusing anything larger than maxImportSize.
+func (ms *MusicService) fetchAttachment(a *discordgo.MessageAttachment) ([]byte, error) {
+	if a.Size > maxImportSize {
+		return nil, ErrImportTooLarge
+	}
+
+	req, err := http.NewRequestWithContext(ms.us.Ctx, http.MethodGet, a.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("could not download attachment: " + resp.Status)
+	}
+	// the size Discord reports is not trusted, one byte more tells the file is too large
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportSize+1))
+	if err == nil && len(data) > maxImportSize {
+		return nil, ErrImportTooLarge
+	}
+	return data, err
+}
+
+// HandlePlaylistExport sends the current queue, or a saved playlist, as a file.
+func (ms *MusicService) HandlePlaylistExport(ctx *gl.CommandContext) *gl.CommandResult {
+	format := ctx.Options.String("format")
+	if format == "" {
+		format = FormatM3U
+	}
+
+	var name string
+	var tracks []miri.SongResult
+	if ctx.Options.Has("name") {
+		_, p, r := ms.findPlaylist(ctx.Options.String("name"), ctx)
+		if r != nil {
+			return r
+		}
+		name, tracks = p.Name, availableTracks(ms.ResolveTracks(p.Tracks))
+	} else if q := ms.GetQueue(ctx.GuildID); q != nil {
+		name, tracks = "queue", q.Tracks()
+	}
+
+	if len(tracks) == 0 {
+		return ctx.UserError(gl.MsgNothingToSave)
+	}
+
+	entries := make([]fileEntry, 0, len(tracks))
+	for _, track := range tracks {
+		entries = append(entries, entryFromTrack(track))
+	}
+
+	data, err := encodePlaylist(format, name, entries)
+	if err != nil {
+		return ctx.InternalError(errors.New("could not export playlist: " + err.Error()))
+	}
+
+	response := ms.us.EmbedMessage(ctx.T(gl.MsgExportedFmt, len(entries), name))
+	response.Files = []*discordgo.File{{
+		Name:        name + "." + format,
+		ContentType: "application/octet-stream",
+		Reader:      bytes.NewReader(data),
+	}}
+	return ms.us.ReplyMessage(response)
+}
+
+// HandlePlaylistImport saves an uploaded playlist file as a playlist, reporting
+// how each of its entries was resolved.
+func (ms *MusicService) HandlePlaylistImport(ctx *gl.CommandContext) *gl.CommandResult {
+	if len(ctx.Attachments) == 0 {
+		return ctx.UserError(gl.MsgImportNoFile)
+	}
+	attachment := ctx.Attachments[0]
+
+	key, r := ms.playlistScope(ctx.Options, ctx)
+	if r != nil {
+		return r
+	}
+
+	data, err := ms.fetchAttachment(attachment)
+	if errors.Is(err, ErrImportTooLarge) {
+		return ctx.UserError(gl.MsgImportTooLarge)
+	}
+	if err != nil {
+		ms.Logger.Warn("could not fetch attachment", "error", err)
+		return ctx.UserError(gl.MsgImportUnreadable)
+	}
+
+	fileName, entries, err := decodePlaylist(detectFormat(attachment.Filename, data), data)
+	if err != nil || len(entries) == 0 {
+		return ctx.UserError(gl.MsgImportUnreadable)
+	}
+	if len(entries) > maxImportEntries {
+		entries = entries[:maxImportEntries]
+	}
+
+	name := strings.TrimSpace(ctx.Options.String("name"))
+	if name == "" {
+		name = fileName
+	}
+	if name == "" {
+		name = strings.TrimSuffix(attachment.Filename, path.Ext(attachment.Filename))
+	}
+	if len([]rune(name)) > gl.DiscordChoiceLimit {
+		return ctx.UserError(gl.MsgPlaylistNameLength)
+	}
+
+	if old, ok := ms.GetPlaylist(key, name); ok && !ms.canEditPlaylist(key, old, ctx) {
+		return ctx.UserError(gl.MsgMissingPerms)
+	}
+
+	playlist := Playlist{Name: name, Owner: ctx.Author.ID, Created: time.Now()}
+	var report string
+	var counts [3]int
+	for n, e := range entries {
+		track, status := ms.resolveEntry(e)
+		counts[status]++
+
+		var line string
+		switch status {
+		case importMatched:
+			line = ctx.T(gl.MsgImportMatchedFmt, ms.us.FormatTrackLine(track))
+		case importAmbiguous:
+			line = ctx.T(gl.MsgImportAmbiguousFmt, e.String(), ms.us.FormatTrackLine(track))
+		default:
+			line = ctx.T(gl.MsgImportNotFoundFmt, e.String())
+		}
+		report += fmt.Sprintf(gl.MsgOrderedList, n+1, line)
+
+		if track != nil {
+			id := fmt.Sprint(track.ID)
+			ms.tracks.Add(id, *track)
+			playlist.Tracks = append(playlist.Tracks, id)
+		}
+	}
+
+	out := ctx.T(gl.MsgImportSummaryFmt, name, counts[importMatched], counts[importAmbiguous], counts[importNotFound]) + report
+	if runes := []rune(out); len(runes) > gl.DiscordEmbedDescriptionLimit {
+		out = string(runes[:gl.DiscordEmbedDescriptionLimit-1]) + "…"
+	}
+
+	if len(playlist.Tracks) == 0 {
+		return ms.us.UserError(out)
+	}
+
+	if err := ms.SavePlaylist(key, playlist); err != nil {
+		return ctx.InternalError(errors.New("could not save playlist: " + err.Error()))
+	}
+	return ms.us.Reply(out)
+}
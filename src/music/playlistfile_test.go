@@ -0,0 +1,53 @@
+package music
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlaylistRoundTrip(t *testing.T) {
+	entries := []fileEntry{
+		{ID: "3135556", Title: "Harder, Better, Faster, Stronger", Artist: "Daft Punk", Album: "Discovery", Duration: 224},
+		{ID: "916424", Title: "Without Me", Artist: "Eminem", Album: "The Eminem Show", Duration: 290},
+	}
+
+	for _, format := range []string{FormatM3U, FormatXSPF, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			data, err := encodePlaylist(format, "mix", entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := detectFormat("", data); got != format {
+				t.Errorf("detectFormat = %q, want %q", got, format)
+			}
+
+			name, got, err := decodePlaylist(format, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != "mix" {
+				t.Errorf("name = %q, want %q", name, "mix")
+			}
+
+			for i := range got {
+				got[i].Album = entries[i].Album // M3U does not carry albums
+			}
+			if !reflect.DeepEqual(got, entries) {
+				t.Errorf("decoded %+v, want %+v", got, entries)
+			}
+		})
+	}
+}
+
+func TestDecodeM3UWithoutIDs(t *testing.T) {
+	data := []byte("#EXTM3U\n#EXTINF:180,Queen - Bohemian Rhapsody\nmusic/queen.mp3\nC:\\Music\\Toto - Africa.flac\n")
+
+	got := decodeM3U(data)
+	want := []fileEntry{
+		{Title: "Bohemian Rhapsody", Artist: "Queen", Duration: 180},
+		{Title: "Africa", Artist: "Toto"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeM3U = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,530 @@
+package music
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/birabittoh/disgord/src/deezer"
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/birabittoh/miri"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	ScopePersonal = "personal"
+	ScopeGuild    = "guild"
+
+	// resolveWorkers bounds the concurrent lookups when resolving track IDs.
+	resolveWorkers = 4
+
+	playlistSaveInteraction = "playlist_save"
+)
+
+var (
+	ErrPlaylistNotFound = errors.New("playlist not found")
+	ErrPlaylistExists   = errors.New("playlist already exists")
+	ErrPlaylistIndex    = errors.New("no track at this position")
+	ErrNoResults        = errors.New("no results found")
+)
+
+// Playlist is a named list of Deezer track IDs.
+type Playlist struct {
+	Name    string    `json:"name"`
+	Owner   string    `json:"owner"`
+	Tracks  []string  `json:"tracks"`
+	Created time.Time `json:"created"`
+}
+
+// PlaylistKey identifies the playlists of a user or a guild in the store.
+func PlaylistKey(scope, id string) string {
+	if scope == ScopePersonal {
+		return "user:" + id
+	}
+	return "guild:" + id
+}
+
+// Playlists returns every saved playlist, keyed by PlaylistKey and lowercase name.
+func (ms *MusicService) Playlists() map[string]map[string]Playlist {
+	return ms.playlists.All()
+}
+
+// GetPlaylist looks up a playlist by name, ignoring case.
+func (ms *MusicService) GetPlaylist(key, name string) (Playlist, bool) {
+	lists, _ := ms.playlists.Get(key)
+	p, ok := lists[strings.ToLower(name)]
+	return p, ok
+}
+
+// SavePlaylist creates or replaces a playlist.
+func (ms *MusicService) SavePlaylist(key string, p Playlist) error {
+	return ms.playlists.Update(key, func(lists *map[string]Playlist) {
+		// the stored map is shared with readers, so it is replaced instead of edited
+		*lists = maps.Clone(*lists)
+		if *lists == nil {
+			*lists = map[string]Playlist{}
+		}
+		(*lists)[strings.ToLower(p.Name)] = p
+	})
+}
+
+// CreatePlaylist saves a new playlist, reporting ErrPlaylistExists instead of
+// replacing one with the same name.
+func (ms *MusicService) CreatePlaylist(key string, p Playlist) error {
+	var err error
+	updateErr := ms.playlists.Update(key, func(lists *map[string]Playlist) {
+		if _, ok := (*lists)[strings.ToLower(p.Name)]; ok {
+			err = ErrPlaylistExists
+			return
+		}
+		*lists = maps.Clone(*lists)
+		if *lists == nil {
+			*lists = map[string]Playlist{}
+		}
+		(*lists)[strings.ToLower(p.Name)] = p
+	})
+	return cmp.Or(err, updateErr)
+}
+
+// UpdatePlaylist applies fn to an existing playlist and saves the result unless
+// fn fails. fn runs while the playlists are locked, so it must not use them.
+func (ms *MusicService) UpdatePlaylist(key, name string, fn func(*Playlist) error) (Playlist, error) {
+	var p Playlist
+	err := ErrPlaylistNotFound
+	updateErr := ms.playlists.Update(key, func(lists *map[string]Playlist) {
+		stored, ok := (*lists)[strings.ToLower(name)]
+		if !ok {
+			return
+		}
+
+		p = stored
+		p.Tracks = slices.Clone(p.Tracks)
+		if err = fn(&p); err != nil {
+			return
+		}
+		*lists = maps.Clone(*lists)
+		(*lists)[strings.ToLower(name)] = p
+	})
+	return p, cmp.Or(err, updateErr)
+}
+
+// DeletePlaylist removes a playlist, reporting ErrPlaylistNotFound if it does not exist.
+func (ms *MusicService) DeletePlaylist(key, name string) error {
+	err := ErrPlaylistNotFound
+	updateErr := ms.playlists.Update(key, func(lists *map[string]Playlist) {
+		if _, ok := (*lists)[strings.ToLower(name)]; ok {
+			*lists = maps.Clone(*lists)
+			delete(*lists, strings.ToLower(name))
+			err = nil
+		}
+	})
+	return cmp.Or(err, updateErr)
+}
+
+// AddToPlaylist appends the best match for query to a playlist.
+func (ms *MusicService) AddToPlaylist(key, name, query string) (*miri.SongResult, error) {
+	if _, ok := ms.GetPlaylist(key, name); !ok {
+		return nil, ErrPlaylistNotFound
+	}
+
+	track, err := ms.findTrack(query)
+	if err != nil {
+		return nil, errors.New("could not search track: " + err.Error())
+	}
+	if track == nil {
+		return nil, ErrNoResults
+	}
+
+	_, err = ms.UpdatePlaylist(key, name, func(p *Playlist) error {
+		p.Tracks = append(p.Tracks, fmt.Sprint(track.ID))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ms.tracks.Add(fmt.Sprint(track.ID), *track)
+	return track, nil
+}
+
+// RemoveTrack removes the track at the 1-based index.
+func (p *Playlist) RemoveTrack(index int) error {
+	if index < 1 || index > len(p.Tracks) {
+		return ErrPlaylistIndex
+	}
+	p.Tracks = slices.Delete(p.Tracks, index-1, index)
+	return nil
+}
+
+// ResolveTracks looks up Deezer track IDs, keeping their order. IDs that cannot
+// be resolved are left nil.
+func (ms *MusicService) ResolveTracks(ids []string) []*miri.SongResult {
+	resolved := make([]*miri.SongResult, len(ids))
+	sem := make(chan struct{}, resolveWorkers)
+	var wg sync.WaitGroup
+
+	for i, id := range ids {
+		if track, ok := ms.tracks.Get(id); ok {
+			resolved[i] = &track
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			track, err := deezer.GetTrack(ms.us.Ctx, id)
+			if err != nil {
+				ms.Logger.Warn("could not resolve track", "id", id, "error", err)
+				return
+			}
+			ms.tracks.Add(id, *track)
+			resolved[i] = track
+		}()
+	}
+	wg.Wait()
+
+	return resolved
+}
+
+// availableTracks drops the tracks that could not be resolved.
+func availableTracks(resolved []*miri.SongResult) []miri.SongResult {
+	tracks := make([]miri.SongResult, 0, len(resolved))
+	for _, track := range resolved {
+		if track != nil {
+			tracks = append(tracks, *track)
+		}
+	}
+	return tracks
+}
+
+// playlistScope returns the store key for the scope option, defaulting to the
+// guild inside servers and to the user in direct messages.
+func (ms *MusicService) playlistScope(opts gl.CommandOptions, ctx *gl.CommandContext) (string, *gl.CommandResult) {
+	scope := opts.String("scope")
+	if scope == "" {
+		scope = ScopeGuild
+		if ctx.GuildID == "" {
+			scope = ScopePersonal
+		}
+	}
+
+	if scope == ScopePersonal {
+		return PlaylistKey(ScopePersonal, ctx.Author.ID), nil
+	}
+	if ctx.GuildID == "" {
+		return "", ctx.UserError(gl.MsgUseInServer)
+	}
+	return PlaylistKey(ScopeGuild, ctx.GuildID), nil
+}
+
+// findPlaylist looks up a playlist by name among the user's own, then the guild's.
+func (ms *MusicService) findPlaylist(name string, ctx *gl.CommandContext) (string, Playlist, *gl.CommandResult) {
+	keys := []string{PlaylistKey(ScopePersonal, ctx.Author.ID)}
+	if ctx.GuildID != "" {
+		keys = append(keys, PlaylistKey(ScopeGuild, ctx.GuildID))
+	}
+
+	for _, key := range keys {
+		if p, ok := ms.GetPlaylist(key, name); ok {
+			return key, p, nil
+		}
+	}
+	return "", Playlist{}, ctx.UserError(gl.MsgPlaylistNotFoundFmt, name)
+}
+
+// canEditPlaylist reports whether the author may change a playlist: personal
+// ones belong to them, guild ones to their creator and server managers.
+func (ms *MusicService) canEditPlaylist(key string, p Playlist, ctx *gl.CommandContext) bool {
+	if key == PlaylistKey(ScopePersonal, ctx.Author.ID) || p.Owner == ctx.Author.ID {
+		return true
+	}
+	return ms.us.HasPermissions(ctx.Member, ctx.ChannelID, ctx.Author.ID, discordgo.PermissionManageGuild)
+}
+
+// playlistError turns a playlist operation error into a reply.
+func (ms *MusicService) playlistError(err error, name string, ctx *gl.CommandContext) *gl.CommandResult {
+	switch {
+	case errors.Is(err, ErrPlaylistNotFound):
+		return ctx.UserError(gl.MsgPlaylistNotFoundFmt, name)
+	case errors.Is(err, ErrPlaylistIndex):
+		return ctx.UserError(gl.MsgInvalidTrackNumber)
+	case errors.Is(err, ErrNoResults):
+		return ctx.UserError(gl.MsgNoResults)
+	}
+	return ms.us.InternalError(errors.New("could not update playlist: " + err.Error()))
+}
+
+// PlaylistForm opens the naming modal when /playlist save is used without a name.
+func (ms *MusicService) PlaylistForm(opts gl.CommandOptions) *discordgo.InteractionResponseData {
+	if opts.Has("name") {
+		return nil
+	}
+	return gl.ModalForm(playlistSaveInteraction+":", gl.MsgPlaylistTitle, PlaylistFormOptions)
+}
+
+var playlistScopeChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{Name: ScopeGuild, Value: ScopeGuild},
+	{Name: ScopePersonal, Value: ScopePersonal},
+}
+
+// PlaylistScopeOption lets commands pick between personal and guild playlists.
+var PlaylistScopeOption = gl.SlashOption{Type: discordgo.ApplicationCommandOptionString, Name: "scope", Description: "Personal or server playlist", Choices: playlistScopeChoices}
+
+var PlaylistFormOptions = []gl.SlashOption{
+	{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "Playlist name", Required: true},
+	PlaylistScopeOption,
+}
+
+// HandlePlaylistAutocomplete suggests the names of the playlists the user can see.
+func (ms *MusicService) HandlePlaylistAutocomplete(focused string, i *discordgo.InteractionCreate) ([]*discordgo.ApplicationCommandOptionChoice, bool) {
+	keys := []string{PlaylistKey(ScopePersonal, gl.InteractionUser(i).ID)}
+	if i.GuildID != "" {
+		keys = append(keys, PlaylistKey(ScopeGuild, i.GuildID))
+	}
+
+	focused = strings.ToLower(focused)
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		lists, _ := ms.playlists.Get(key)
+		for name, p := range lists {
+			if seen[name] || !strings.Contains(name, focused) || len(choices) == gl.DiscordMaxChoices {
+				continue
+			}
+			seen[name] = true
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: p.Name, Value: p.Name})
+		}
+	}
+
+	slices.SortFunc(choices, func(a, b *discordgo.ApplicationCommandOptionChoice) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return choices, true
+}
+
+// HandlePlaylistSave saves the current queue as a playlist.
+func (ms *MusicService) HandlePlaylistSave(ctx *gl.CommandContext) *gl.CommandResult {
+	name := strings.TrimSpace(ctx.Options.String("name"))
+	if name == "" {
+		return ctx.UserError(gl.MsgMissingOptionFmt, "name")
+	}
+	return ms.savePlaylist(name, ctx.Options, ctx, false)
+}
+
+// HandlePlaylistSaveForm saves the current queue under the name submitted through
+// the modal. From the button offering to replace a playlist, arg is its scope and name.
+func (ms *MusicService) HandlePlaylistSaveForm(arg string, ctx *gl.CommandContext) *gl.CommandResult {
+	i := ctx.Interaction
+	if scope, name, ok := strings.Cut(arg, ":"); ok {
+		return ms.savePlaylist(name, gl.CommandOptions{"scope": scope}, ctx, true)
+	}
+
+	opts, err := gl.OptionsFromModal(PlaylistFormOptions, i.ModalSubmitData())
+	if err != nil {
+		return ms.us.UserError(ctx.TError(err))
+	}
+	return ms.savePlaylist(opts.String("name"), opts, ctx, false)
+}
+
+// savePlaylist saves the current queue as a playlist. One with the same name is
+// only replaced when replace is set; otherwise the user is asked to confirm.
+func (ms *MusicService) savePlaylist(name string, opts gl.CommandOptions, ctx *gl.CommandContext, replace bool) *gl.CommandResult {
+	if len([]rune(name)) > gl.DiscordChoiceLimit {
+		return ctx.UserError(gl.MsgPlaylistNameLength)
+	}
+
+	key, r := ms.playlistScope(opts, ctx)
+	if r != nil {
+		return r
+	}
+
+	q := ms.GetQueue(ctx.GuildID)
+	if q == nil || len(q.Tracks()) == 0 {
+		return ctx.UserError(gl.MsgNothingToSave)
+	}
+
+	if old, ok := ms.GetPlaylist(key, name); ok && !ms.canEditPlaylist(key, old, ctx) {
+		return ctx.UserError(gl.MsgMissingPerms)
+	}
+
+	playlist := Playlist{Name: name, Owner: ctx.Author.ID, Created: time.Now()}
+	for _, track := range q.Tracks() {
+		playlist.Tracks = append(playlist.Tracks, fmt.Sprint(track.ID))
+	}
+
+	save := ms.CreatePlaylist
+	if replace {
+		save = ms.SavePlaylist
+	}
+	err := save(key, playlist)
+	if errors.Is(err, ErrPlaylistExists) {
+		return ms.replacePrompt(key, name, ctx)
+	}
+	if err != nil {
+		return ms.us.InternalError(errors.New("could not save playlist: " + err.Error()))
+	}
+
+	return ms.us.Reply(ctx.T(gl.MsgPlaylistSavedFmt, len(playlist.Tracks), name))
+}
+
+// replacePrompt tells that playlist name already exists, with a button to replace
+// it when its name fits in the button.
+func (ms *MusicService) replacePrompt(key, name string, ctx *gl.CommandContext) *gl.CommandResult {
+	scope := ScopeGuild
+	if key == PlaylistKey(ScopePersonal, ctx.Author.ID) {
+		scope = ScopePersonal
+	}
+
+	r := ctx.UserError(gl.MsgPlaylistExistsFmt, name)
+	if customID := playlistSaveInteraction + ":" + scope + ":" + name; len(customID) <= gl.DiscordCustomIDLimit {
+		r.WithComponents(discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{Label: ctx.T(gl.MsgPlaylistReplace), Style: discordgo.DangerButton, CustomID: customID},
+		}})
+	}
+	return r
+}
+
+// HandlePlaylistLoad adds every track of a playlist to the queue.
+func (ms *MusicService) HandlePlaylistLoad(ctx *gl.CommandContext) *gl.CommandResult {
+	_, p, r := ms.findPlaylist(ctx.Options.String("name"), ctx)
+	if r != nil {
+		return r
+	}
+
+	tracks := availableTracks(ms.ResolveTracks(p.Tracks))
+	if len(tracks) == 0 {
+		return ctx.UserError(gl.MsgPlaylistEmpty)
+	}
+
+	q, r := ms.joinQueue(ctx)
+	if r != nil {
+		return r
+	}
+
+	q.AddTracks(ms, tracks, ctx.Author.ID)
+	out := ctx.T(gl.MsgPlaylistLoadedFmt, len(tracks), p.Name)
+	if missing := len(p.Tracks) - len(tracks); missing > 0 {
+		out += " " + ctx.T(gl.MsgPlaylistMissingFmt, missing)
+	}
+	return ms.us.Reply(out)
+}
+
+// HandlePlaylistAdd appends the best match for a query to a playlist.
+func (ms *MusicService) HandlePlaylistAdd(ctx *gl.CommandContext) *gl.CommandResult {
+	key, p, r := ms.findPlaylist(ctx.Options.String("name"), ctx)
+	if r != nil {
+		return r
+	}
+	if !ms.canEditPlaylist(key, p, ctx) {
+		return ctx.UserError(gl.MsgMissingPerms)
+	}
+
+	track, err := ms.AddToPlaylist(key, p.Name, ctx.Options.String("query"))
+	if err != nil {
+		return ms.playlistError(err, p.Name, ctx)
+	}
+
+	return ctx.Reply(gl.MsgPlaylistAddedFmt, ms.us.FormatTrackLine(track), p.Name)
+}
+
+// HandlePlaylistRemove removes a track from a playlist by position.
+func (ms *MusicService) HandlePlaylistRemove(ctx *gl.CommandContext) *gl.CommandResult {
+	key, p, r := ms.findPlaylist(ctx.Options.String("name"), ctx)
+	if r != nil {
+		return r
+	}
+	if !ms.canEditPlaylist(key, p, ctx) {
+		return ctx.UserError(gl.MsgMissingPerms)
+	}
+
+	index, _ := ctx.Options.Int("index")
+	_, err := ms.UpdatePlaylist(key, p.Name, func(p *Playlist) error {
+		return p.RemoveTrack(int(index))
+	})
+	if err != nil {
+		return ms.playlistError(err, p.Name, ctx)
+	}
+
+	return ctx.Reply(gl.MsgPlaylistRemovedFmt, index, p.Name)
+}
+
+// HandlePlaylistList lists the playlists of the user and of the guild.
+func (ms *MusicService) HandlePlaylistList(ctx *gl.CommandContext) *gl.CommandResult {
+	scopes := []string{ScopePersonal, ScopeGuild}
+	if s := ctx.Options.String("scope"); s != "" {
+		scopes = []string{s}
+	}
+
+	var out string
+	for _, scope := range scopes {
+		id := ctx.Author.ID
+		if scope == ScopeGuild {
+			if ctx.GuildID == "" {
+				continue
+			}
+			id = ctx.GuildID
+		}
+
+		lists, _ := ms.playlists.Get(PlaylistKey(scope, id))
+		names := make([]string, 0, len(lists))
+		for name := range lists {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		for _, name := range names {
+			p := lists[name]
+			out += fmt.Sprintf(gl.MsgUnorderedList, ctx.T(gl.MsgPlaylistLineFmt, p.Name, len(p.Tracks), scope))
+		}
+	}
+
+	if out == "" {
+		return ctx.Reply(gl.MsgNoPlaylists)
+	}
+	return ms.us.Reply(out)
+}
+
+// HandlePlaylistShow lists the tracks of a playlist.
+func (ms *MusicService) HandlePlaylistShow(ctx *gl.CommandContext) *gl.CommandResult {
+	_, p, r := ms.findPlaylist(ctx.Options.String("name"), ctx)
+	if r != nil {
+		return r
+	}
+
+	out := ctx.T(gl.MsgPlaylistHeaderFmt, p.Name, len(p.Tracks))
+	for n, track := range ms.ResolveTracks(p.Tracks) {
+		line := fmt.Sprintf(gl.MsgOrderedList, n+1, ctx.T(gl.MsgTrackUnavailable))
+		if track != nil {
+			line = fmt.Sprintf(gl.MsgOrderedList, n+1, ms.us.FormatTrackLine(track))
+		}
+		if len(out)+len(line) > gl.DiscordEmbedDescriptionLimit {
+			break
+		}
+		out += line
+	}
+	return ms.us.Reply(out)
+}
+
+// HandlePlaylistDelete deletes a playlist.
+func (ms *MusicService) HandlePlaylistDelete(ctx *gl.CommandContext) *gl.CommandResult {
+	key, p, r := ms.findPlaylist(ctx.Options.String("name"), ctx)
+	if r != nil {
+		return r
+	}
+	if !ms.canEditPlaylist(key, p, ctx) {
+		return ctx.UserError(gl.MsgMissingPerms)
+	}
+
+	if err := ms.DeletePlaylist(key, p.Name); err != nil {
+		return ms.playlistError(err, p.Name, ctx)
+	}
+	return ctx.Reply(gl.MsgPlaylistDeletedFmt, p.Name)
+}
@@ -2,33 +2,80 @@ package music
 
 import (
 	"context"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
 
+	gl "github.com/birabittoh/disgord/src/globals"
 	"github.com/birabittoh/miri"
 	"github.com/bwmarrin/discordgo"
 )
 
+// backLimit is how many played tracks are kept for the previous command.
+const backLimit = 20
+
+// queueItem is a queued track along with the user who asked for it.
+type queueItem struct {
+	track     miri.SongResult
+	requester string
+}
+
 type Queue struct {
 	nowPlaying  *miri.SongResult
-	items       []miri.SongResult
+	requester   string // user who asked for nowPlaying, empty when unknown
+	started     time.Time
+	items       []queueItem
+	history     []queueItem // played tracks, oldest first
+	back        *queueItem  // track to replay instead of advancing
 	audioStream *Audio
 	vc          *discordgo.VoiceConnection
+	guildID     string
 	channelID   string
 	client      *miri.Client
 	ctx         context.Context
+	loop        bool
+	skipped     bool
+	volume      int
+
+	// now-playing and live lyrics messages, posted where music was last requested
+	npMu        sync.Mutex
+	textChannel string
+	npMessageID string
+	lyrics      *liveLyrics
 }
 
-func (q *Queue) AddTrack(ms *MusicService, track *miri.SongResult) {
-	q.AddTracks(ms, []miri.SongResult{*track})
+func (q *Queue) AddTrack(ms *MusicService, track *miri.SongResult, requester string) {
+	q.AddTracks(ms, []miri.SongResult{*track}, requester)
 }
 
-func (q *Queue) AddTracks(ms *MusicService, tracks []miri.SongResult) {
-	q.items = append(q.items, tracks...)
+// AddTracks enqueues tracks on behalf of requester, starting playback if idle.
+func (q *Queue) AddTracks(ms *MusicService, tracks []miri.SongResult, requester string) {
+	q.InsertTracks(ms, tracks, requester, 0)
+}
+
+// InsertTracks puts tracks at position, counting from 1 as shown by the queue
+// command; 0 or a position past the end appends them. It returns where the
+// first track ended up, or 0 when it started playing right away.
+func (q *Queue) InsertTracks(ms *MusicService, tracks []miri.SongResult, requester string, position int) int {
+	if position < 1 || position > len(q.items) {
+		position = len(q.items) + 1
+	}
+
+	items := make([]queueItem, 0, len(tracks))
+	for _, track := range tracks {
+		items = append(items, queueItem{track: track, requester: requester})
+	}
+	q.items = slices.Insert(q.items, position-1, items...)
+
 	if q.nowPlaying == nil {
 		err := q.PlayNext(ms, false)
 		if err != nil {
 			ms.Logger.Error("could not play next track", "error", err)
 		}
+		return position - 1
 	}
+	return position
 }
 
 func (q *Queue) PlayNext(ms *MusicService, skip bool) (err error) {
@@ -37,18 +84,39 @@ func (q *Queue) PlayNext(ms *MusicService, skip bool) (err error) {
 	}
 
 	if q.audioStream != nil && q.audioStream.playing {
+		q.skipped = skip
 		q.audioStream.Stop()
 		if skip {
 			return nil
 		}
 	}
 
+	if q.nowPlaying != nil {
+		ms.recordPlay(q.vc.GuildID, q, q.skipped)
+		current := queueItem{track: *q.nowPlaying, requester: q.requester}
+		switch {
+		case q.back != nil:
+			q.items = append([]queueItem{*q.back, current}, q.items...)
+			q.back = nil
+		case q.loop && !q.skipped:
+			q.items = append([]queueItem{current}, q.items...)
+		default:
+			q.history = append(q.history, current)
+			if len(q.history) > backLimit {
+				q.history = q.history[len(q.history)-backLimit:]
+			}
+		}
+		q.nowPlaying = nil
+	}
+	q.skipped = false
+
 	if len(q.items) == 0 {
 		ms.DeleteQueue(q.vc.GuildID)
 		return nil
 	}
 
-	q.nowPlaying = &q.items[0]
+	next := q.items[0]
+	q.nowPlaying, q.requester, q.started = &next.track, next.requester, time.Now()
 	q.items = q.items[1:]
 	q.audioStream, err = NewAudio(q.nowPlaying, q.vc, ms, 0)
 	if err != nil {
@@ -57,9 +125,32 @@ func (q *Queue) PlayNext(ms *MusicService, skip bool) (err error) {
 
 	q.audioStream.onFinish = func() { q.PlayNext(ms, false) }
 	q.audioStream.Monitor()
+	go ms.UpdateNowPlaying(q)
 	return
 }
 
+// Previous plays the last track again, putting the current one back at the top
+// of the queue. It reports false when nothing was played before.
+func (q *Queue) Previous(ms *MusicService) (track miri.SongResult, ok bool, err error) {
+	if len(q.history) == 0 {
+		return
+	}
+
+	last := q.history[len(q.history)-1]
+	q.history = q.history[:len(q.history)-1]
+	if q.nowPlaying == nil {
+		q.items = append([]queueItem{last}, q.items...)
+	} else {
+		q.back = &last
+	}
+	return last.track, true, q.PlayNext(ms, true)
+}
+
+// Replay restarts the current track from the beginning.
+func (q *Queue) Replay(ms *MusicService) error {
+	return q.Seek(ms, 0)
+}
+
 func (q *Queue) Seek(ms *MusicService, seekTo int) (err error) {
 	if q.vc == nil || ms.us.Ctx == nil {
 		return
@@ -69,6 +160,7 @@ func (q *Queue) Seek(ms *MusicService, seekTo int) (err error) {
 		return
 	}
 
+	paused := q.audioStream.Paused()
 	q.audioStream.onFinish = nil
 	q.audioStream.Stop()
 
@@ -76,6 +168,9 @@ func (q *Queue) Seek(ms *MusicService, seekTo int) (err error) {
 	if err != nil {
 		return
 	}
+	if paused {
+		q.audioStream.Pause()
+	}
 	q.audioStream.onFinish = func() { q.PlayNext(ms, false) }
 	q.audioStream.Monitor()
 	return
@@ -96,14 +191,132 @@ func (q *Queue) Stop() {
 }
 
 func (q *Queue) Clear() {
-	q.items = []miri.SongResult{}
+	q.items = []queueItem{}
+}
+
+// Remove drops the track at index, counting from 1 as shown by the queue command.
+func (q *Queue) Remove(index int) (track miri.SongResult, ok bool) {
+	if index < 1 || index > len(q.items) {
+		return
+	}
+
+	track = q.items[index-1].track
+	q.items = append(q.items[:index-1], q.items[index:]...)
+	return track, true
+}
+
+// Move puts the track at from in position to, both counting from 1.
+func (q *Queue) Move(from, to int) (track miri.SongResult, ok bool) {
+	if from < 1 || from > len(q.items) || to < 1 || to > len(q.items) {
+		return
+	}
+
+	item := q.items[from-1]
+	q.items = slices.Insert(slices.Delete(q.items, from-1, from), to-1, item)
+	return item.track, true
+}
+
+func (q *Queue) Volume() int {
+	return q.volume
+}
+
+// SetVolume changes the volume percentage, restarting the current track at its
+// current position so that the change is audible right away.
+func (q *Queue) SetVolume(ms *MusicService, volume int) error {
+	q.volume = max(0, min(volume, gl.AudioMaxVolume))
+	if q.audioStream == nil || !q.audioStream.playing {
+		return nil
+	}
+	return q.Seek(ms, int(q.audioStream.Position().Seconds()))
+}
+
+// TogglePause pauses or resumes the current track and reports whether it is now paused.
+func (q *Queue) TogglePause() bool {
+	if q.audioStream == nil {
+		return false
+	}
+
+	if q.audioStream.Paused() {
+		q.audioStream.Resume()
+	} else {
+		q.audioStream.Pause()
+	}
+	return q.audioStream.Paused()
+}
+
+func (q *Queue) Paused() bool {
+	return q.audioStream != nil && q.audioStream.Paused()
+}
+
+// ToggleLoop switches repeating the current track and reports the new state.
+func (q *Queue) ToggleLoop() bool {
+	q.loop = !q.loop
+	return q.loop
+}
+
+func (q *Queue) Loop() bool {
+	return q.loop
+}
+
+func (q *Queue) Shuffle() {
+	rand.Shuffle(len(q.items), func(i, j int) {
+		q.items[i], q.items[j] = q.items[j], q.items[i]
+	})
+}
+
+// SetTextChannel records where music was last requested, so the now-playing
+// message follows the conversation.
+func (q *Queue) SetTextChannel(channelID string) {
+	if channelID != "" {
+		q.textChannel = channelID
+	}
 }
 
 func (q *Queue) Tracks() []miri.SongResult {
+	tracks := make([]miri.SongResult, 0, len(q.items)+1)
 	if q.nowPlaying != nil {
-		return append([]miri.SongResult{*q.nowPlaying}, q.items...)
+		tracks = append(tracks, *q.nowPlaying)
+	}
+	for _, item := range q.items {
+		tracks = append(tracks, item.track)
+	}
+	return tracks
+}
+
+// ETA returns how long until the upcoming track at position plays, counting from 1.
+func (q *Queue) ETA(position int) time.Duration {
+	if position < 1 || q.nowPlaying == nil {
+		return 0
+	}
+
+	eta := time.Duration(q.nowPlaying.Duration) * time.Second
+	if q.audioStream != nil {
+		eta -= q.audioStream.Position()
+	}
+	for _, item := range q.items[:min(position-1, len(q.items))] {
+		eta += time.Duration(item.track.Duration) * time.Second
+	}
+	return max(eta, 0)
+}
+
+// requesterAt returns who asked for the upcoming track at position, or for the current one at 0.
+func (q *Queue) requesterAt(position int) string {
+	if position == 0 {
+		return q.requester
+	}
+	if position < 0 || position > len(q.items) {
+		return ""
+	}
+	return q.items[position-1].requester
+}
+
+// History returns the tracks played before the current one, most recent first.
+func (q *Queue) History() []miri.SongResult {
+	tracks := make([]miri.SongResult, 0, len(q.history))
+	for _, item := range slices.Backward(q.history) {
+		tracks = append(tracks, item.track)
 	}
-	return q.items
+	return tracks
 }
 
 func (q *Queue) VoiceChannelID() string {
@@ -1,12 +1,15 @@
 package music
 
 import (
+	"errors"
 	"log/slog"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/birabittoh/disgord/src/deezer"
 	"github.com/birabittoh/disgord/src/globals"
+	"github.com/birabittoh/disgord/src/store"
 	"github.com/birabittoh/miri"
 	"github.com/bwmarrin/discordgo"
 	lru "github.com/hashicorp/golang-lru/v2"
@@ -15,6 +18,7 @@ import (
 
 type PendingSearch struct {
 	Results   []miri.SongResult
+	Selected  []int // indexes of the results picked from the select menu
 	MessageID string
 }
 
@@ -25,6 +29,15 @@ type MusicService struct {
 	Logger   *slog.Logger
 	Queues   map[string]*Queue
 	Searches *lru.Cache[string, *PendingSearch]
+
+	suggestions *lru.Cache[string, []miri.SongResult]
+	playlists   *store.Collection[map[string]Playlist]
+	tracks      *lru.Cache[string, miri.SongResult] // resolved playlist tracks by ID
+	history     *store.Log[PlayRecord]
+	lyricsCache *lru.Cache[string, string] // lyrics by track ID
+	details     *lru.Cache[string, deezer.TrackDetails]
+	acMu        sync.Mutex
+	acSeq       map[string]uint64
 }
 
 func NewMusicService(us *globals.UtilsService) (*MusicService, error) {
@@ -33,6 +46,31 @@ func NewMusicService(us *globals.UtilsService) (*MusicService, error) {
 		return nil, err
 	}
 
+	suggestions, err := lru.New[string, []miri.SongResult](256)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks, err := lru.New[string, miri.SongResult](1024)
+	if err != nil {
+		return nil, err
+	}
+
+	lyricsCache, err := lru.New[string, string](64)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := lru.New[string, deezer.TrackDetails](256)
+	if err != nil {
+		return nil, err
+	}
+
+	playlists, err := store.NewCollection[map[string]Playlist](us.Store, "playlists")
+	if err != nil {
+		return nil, err
+	}
+
 	logger := slog.New(tint.NewHandler(os.Stdout, &tint.Options{
 		Level:      us.Config.LogLevel,
 		TimeFormat: us.Config.TimeFormat,
@@ -45,13 +83,23 @@ func NewMusicService(us *globals.UtilsService) (*MusicService, error) {
 		us.Config.DeezerPassword,
 	)
 
-	return &MusicService{
+	ms := &MusicService{
 		us:       us,
 		arl:      arlMgr,
 		Logger:   logger,
 		Queues:   make(map[string]*Queue),
 		Searches: cache,
-	}, nil
+
+		suggestions: suggestions,
+		playlists:   playlists,
+		tracks:      tracks,
+		history:     store.NewLog[PlayRecord](us.Store, "history"),
+		lyricsCache: lyricsCache,
+		details:     details,
+		acSeq:       make(map[string]uint64),
+	}
+	go ms.pruneHistory()
+	return ms, nil
 }
 
 func (ms *MusicService) GetVoiceConnection(vc string, guildID string) (voice *discordgo.VoiceConnection, err error) {
@@ -83,9 +131,11 @@ func (ms *MusicService) GetOrCreateQueue(vc *discordgo.VoiceConnection, channelI
 
 		q = &Queue{
 			vc:        vc,
+			guildID:   vc.GuildID,
 			channelID: channelID,
 			ctx:       ms.us.Ctx,
 			client:    client,
+			volume:    globals.AudioVolume,
 		}
 		ms.Queues[vc.GuildID] = q
 	} else {
@@ -96,6 +146,48 @@ func (ms *MusicService) GetOrCreateQueue(vc *discordgo.VoiceConnection, channelI
 	return q, nil
 }
 
+// controlledQueue returns the queue of guildID if the user is listening to it,
+// or the message explaining why they cannot control it.
+func (ms *MusicService) controlledQueue(member *discordgo.Member, guildID, userID string) (q *Queue, response string) {
+	response, g, vc := ms.us.GetVoiceChannelID(member, guildID, userID)
+	if response != "" {
+		return
+	}
+
+	q = ms.GetQueue(g.ID)
+	if q == nil {
+		return nil, globals.MsgNothingIsPlaying
+	}
+
+	if vc != q.VoiceChannelID() {
+		return nil, globals.MsgSameVoiceChannel
+	}
+	return q, ""
+}
+
+// joinQueue connects to the voice channel of the user who invoked ctx and
+// returns the queue playing there, or the result explaining why it could not.
+func (ms *MusicService) joinQueue(ctx *globals.CommandContext) (*Queue, *globals.CommandResult) {
+	r, _, vc := ms.us.GetVoiceChannelID(ctx.Member, ctx.GuildID, ctx.Author.ID)
+	if r != "" {
+		return nil, ctx.UserError(r)
+	}
+
+	voice, err := ms.GetVoiceConnection(vc, ctx.GuildID)
+	if err != nil {
+		return nil, ms.us.InternalError(err)
+	}
+
+	q, err := ms.GetOrCreateQueue(voice, vc)
+	if err != nil {
+		voice.Disconnect(ms.us.Ctx)
+		return nil, ms.us.InternalError(errors.New("could not create queue: " + err.Error()))
+	}
+
+	ms.setNowPlayingChannel(q, ctx.ChannelID)
+	return q, nil
+}
+
 func (ms *MusicService) newMiriClient() (*miri.Client, error) {
 	arl, err := ms.arl.EnsureARL()
 	if err != nil {
@@ -153,7 +245,10 @@ func (ms *MusicService) DeleteQueue(guildID string) {
 
 	ms.Logger.Debug("Deleting queue for guild", "guildID", guildID)
 
+	// stopping is not a skip, unless one was already on its way
+	ms.recordPlay(guildID, q, q.skipped)
 	q.Stop()
+	go ms.closeNowPlaying(q)
 	delete(ms.Queues, guildID)
 }
 
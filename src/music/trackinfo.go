@@ -0,0 +1,49 @@
+package music
+
+import (
+	"fmt"
+
+	"github.com/birabittoh/disgord/src/deezer"
+	gl "github.com/birabittoh/disgord/src/globals"
+	"github.com/birabittoh/miri"
+	"github.com/bwmarrin/discordgo"
+)
+
+// trackMessage renders track with its full details in locale. When q is set, it also
+// shows who asked for it and, for upcoming tracks, when it plays; position
+// counts from 1 as shown by the queue command, 0 being the current track.
+func (ms *MusicService) trackMessage(locale string, track *miri.SongResult, q *Queue, position int) *discordgo.MessageSend {
+	info := ms.trackDetails(track)
+	if q != nil {
+		info.Requester = q.requesterAt(position)
+		info.Position = position
+		info.ETA = q.ETA(position)
+	}
+	return ms.us.EmbedTrackDetails(locale, track, info)
+}
+
+// trackDetails looks up the metadata of track that searches leave out. The
+// lookup is cached, and failures only leave the details out.
+func (ms *MusicService) trackDetails(track *miri.SongResult) gl.TrackInfo {
+	id := fmt.Sprint(track.ID)
+	details, ok := ms.details.Get(id)
+	if !ok {
+		d, err := deezer.GetTrackDetails(ms.us.Ctx, id)
+		if err != nil {
+			ms.Logger.Debug("could not get track details", "id", id, "error", err)
+			return gl.TrackInfo{Link: trackLink(id)}
+		}
+		details = *d
+		ms.details.Add(id, details)
+	}
+
+	if details.Link == "" {
+		details.Link = trackLink(id)
+	}
+	return gl.TrackInfo{
+		Link:        details.Link,
+		ReleaseDate: details.ReleaseDate,
+		Explicit:    details.Explicit,
+		BPM:         details.BPM,
+	}
+}
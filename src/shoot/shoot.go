@@ -5,10 +5,10 @@ import (
 	"log/slog"
 	"math/rand/v2"
 	"os"
+	"slices"
 	"time"
 
 	gl "github.com/birabittoh/disgord/src/globals"
-	"github.com/bwmarrin/discordgo"
 	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/lmittmann/tint"
 )
@@ -95,13 +95,13 @@ func (ss *ShootService) GetMagazine(userID string) (q *Magazine) {
 	return
 }
 
-func (ss *ShootService) HandleShoot(args string, m *discordgo.MessageCreate) *discordgo.MessageSend {
-	response, guild, voiceChannelID := ss.us.GetVoiceChannelID(m.Member, m.GuildID, m.Author.ID)
+func (ss *ShootService) HandleShoot(ctx *gl.CommandContext) *gl.CommandResult {
+	response, guild, voiceChannelID := ss.us.GetVoiceChannelID(ctx.Member, ctx.GuildID, ctx.Author.ID)
 	if voiceChannelID == "" {
-		return ss.us.EmbedMessage(response)
+		return ctx.UserError(response)
 	}
 
-	killerID := m.Author.ID
+	killerID := ctx.Author.ID
 	var allMembers []string
 	var err error
 	for _, vs := range guild.VoiceStates {
@@ -126,12 +126,20 @@ func (ss *ShootService) HandleShoot(args string, m *discordgo.MessageCreate) *di
 	}
 
 	if len(allMembers) == 0 {
-		return ss.us.EmbedMessage(fmt.Sprintf(gl.MsgNoOtherUsersFmt, voiceChannelID))
+		return ctx.UserError(gl.MsgNoOtherUsersFmt, voiceChannelID)
+	}
+
+	target := ctx.Options.User("target")
+	if target != "" {
+		if !slices.Contains(allMembers, target) {
+			return ctx.UserError(gl.MsgTargetNotHere)
+		}
+		allMembers = []string{target}
 	}
 
 	magazine := ss.GetMagazine(killerID)
 	if !magazine.Shoot() {
-		return ss.us.EmbedMessage(gl.MsgOutOfBullets)
+		return ctx.Reply(gl.MsgOutOfBullets)
 	}
 
 	victimID := killerID
@@ -139,11 +147,11 @@ func (ss *ShootService) HandleShoot(args string, m *discordgo.MessageCreate) *di
 		victimID = allMembers[rand.IntN(len(allMembers))]
 	}
 
-	err = ss.us.Session.GuildMemberMove(m.GuildID, victimID, nil)
+	err = ss.us.Session.GuildMemberMove(ctx.GuildID, victimID, nil)
 	if err != nil {
 		ss.logger.Error("could not kick user", "error", err)
-		return ss.us.EmbedMessage(gl.MsgCantKickUser)
+		return ctx.UserError(gl.MsgCantKickUser)
 	}
 
-	return ss.us.EmbedMessage(fmt.Sprintf(gl.MsgShootFmt, victimID, magazine.String()))
+	return ctx.Reply(gl.MsgShootFmt, victimID, ctx.T(gl.MsgMagazineFmt, magazine.Left(), magazine.Size()))
 }
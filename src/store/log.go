@@ -0,0 +1,136 @@
+package store
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Log is an append-only list of records, kept as one JSON document per line.
+type Log[T any] struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewLog[T any](s *Store, name string) *Log[T] {
+	return &Log[T]{path: filepath.Join(s.dir, name+".jsonl")}
+}
+
+func (l *Log[T]) Append(v T) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Each calls fn for every record, oldest first, until fn returns false.
+// Lines that cannot be decoded are skipped. Records appended in the meantime
+// are not visited, and appending does not wait for Each.
+func (l *Log[T]) Each(fn func(T) bool) error {
+	f, size, err := l.open()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := newScanner(io.LimitReader(f, size))
+	for scanner.Scan() {
+		var v T
+		if json.Unmarshal(scanner.Bytes(), &v) != nil {
+			continue
+		}
+		if !fn(v) {
+			break
+		}
+	}
+	return scanner.Err()
+}
+
+// Retain rewrites the log keeping only the records for which keep returns
+// true, and reports how many were dropped.
+func (l *Log[T]) Retain(keep func(T) bool) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	tmp := l.path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp) // nothing left to remove once renamed
+
+	w := bufio.NewWriter(out)
+	dropped := 0
+	scanner := newScanner(f)
+	for scanner.Scan() {
+		var v T
+		if json.Unmarshal(scanner.Bytes(), &v) != nil || !keep(v) {
+			dropped++
+			continue
+		}
+		w.Write(scanner.Bytes())
+		w.WriteByte('\n')
+	}
+	if err := cmp.Or(scanner.Err(), w.Flush(), out.Close()); err != nil {
+		return 0, err
+	}
+
+	if dropped == 0 {
+		return 0, nil
+	}
+	return dropped, os.Rename(tmp, l.path)
+}
+
+// open opens the log along with its current size, so that readers can stop at
+// the records written so far.
+func (l *Log[T]) open() (*os.File, int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return scanner
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store keeps small JSON documents in a data directory.
+type Store struct {
+	dir string
+}
+
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.New("could not create data directory: " + err.Error())
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Dir returns the data directory of the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Load decodes the document name into v. A missing document leaves v untouched.
+func (s *Store) Load(name string, v any) error {
+	data, err := os.ReadFile(filepath.Join(s.dir, name+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Save atomically replaces the document name with the JSON encoding of v.
+func (s *Store) Save(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.dir, name+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Collection is a persistent map of values, saved as a whole on every change.
+type Collection[T any] struct {
+	mu    sync.RWMutex
+	store *Store
+	name  string
+	items map[string]T
+}
+
+func NewCollection[T any](s *Store, name string) (*Collection[T], error) {
+	c := &Collection[T]{store: s, name: name, items: map[string]T{}}
+	if err := s.Load(name, &c.items); err != nil {
+		return nil, errors.New("could not load " + name + ": " + err.Error())
+	}
+	return c, nil
+}
+
+func (c *Collection[T]) Get(key string) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.items[key]
+	return v, ok
+}
+
+// All returns a copy of every item in the collection.
+func (c *Collection[T]) All() map[string]T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.items)
+}
+
+func (c *Collection[T]) Set(key string, v T) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = v
+	return c.store.Save(c.name, c.items)
+}
+
+// Update applies fn to the item at key, starting from the zero value if it is missing.
+func (c *Collection[T]) Update(key string, fn func(*T)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v := c.items[key]
+	fn(&v)
+	c.items[key] = v
+	return c.store.Save(c.name, c.items)
+}
+
+func (c *Collection[T]) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[key]; !ok {
+		return nil
+	}
+	delete(c.items, key)
+	return c.store.Save(c.name, c.items)
+}
@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/birabittoh/disgord/src/bot"
+)
+
+type CustomResponsePayload struct {
+	Response string `json:"response"`
+}
+
+func customStatus(err error) int {
+	switch {
+	case errors.Is(err, bot.ErrCustomNotFound), errors.Is(err, bot.ErrAutoResponseNotFnd):
+		return http.StatusNotFound
+	case errors.Is(err, bot.ErrCustomReserved):
+		return http.StatusConflict
+	case errors.Is(err, bot.ErrCustomName), errors.Is(err, bot.ErrCustomResponse),
+		errors.Is(err, bot.ErrCustomLimit), errors.Is(err, bot.ErrAutoResponseLimit):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// botService returns the bot service, replying with an error when it is disabled.
+func (ui *UIService) botService(w http.ResponseWriter) *bot.BotService {
+	if !ui.IsBotEnabled() {
+		jsonError(w, "Bot is disabled", http.StatusServiceUnavailable)
+		return nil
+	}
+	return ui.bs
+}
+
+// decodeCustomResponse reads the response of a custom command or auto-response.
+func decodeCustomResponse(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var payload CustomResponsePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || strings.TrimSpace(payload.Response) == "" {
+		jsonError(w, "Invalid JSON payload", http.StatusBadRequest)
+		return "", false
+	}
+	return payload.Response, true
+}
+
+func (ui *UIService) customHandler(w http.ResponseWriter, r *http.Request) {
+	if !ui.IsBotEnabled() {
+		jsonSuccess(w, map[string]any{"commands": []any{}, "triggers": []any{}})
+		return
+	}
+
+	commands, responses := ui.bs.CustomCommands(r.PathValue("guild_id"))
+	jsonSuccess(w, map[string]any{
+		"prefix":   ui.bs.US.Config.Prefix,
+		"commands": commands,
+		"triggers": responses,
+	})
+}
+
+func (ui *UIService) customCommandSetHandler(w http.ResponseWriter, r *http.Request) {
+	bs := ui.botService(w)
+	if bs == nil {
+		return
+	}
+
+	response, ok := decodeCustomResponse(w, r)
+	if !ok {
+		return
+	}
+
+	err := bs.SetCustomCommand(r.PathValue("guild_id"), bot.CustomCommand{Name: r.PathValue("name"), Response: response})
+	if err != nil {
+		jsonError(w, err.Error(), customStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ui *UIService) customCommandDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	bs := ui.botService(w)
+	if bs == nil {
+		return
+	}
+
+	if err := bs.DeleteCustomCommand(r.PathValue("guild_id"), r.PathValue("name")); err != nil {
+		jsonError(w, err.Error(), customStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ui *UIService) autoResponseSetHandler(w http.ResponseWriter, r *http.Request) {
+	bs := ui.botService(w)
+	if bs == nil {
+		return
+	}
+
+	response, ok := decodeCustomResponse(w, r)
+	if !ok {
+		return
+	}
+
+	err := bs.SetAutoResponse(r.PathValue("guild_id"), bot.AutoResponse{Trigger: r.PathValue("trigger"), Response: response})
+	if err != nil {
+		jsonError(w, err.Error(), customStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ui *UIService) autoResponseDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	bs := ui.botService(w)
+	if bs == nil {
+		return
+	}
+
+	if err := bs.DeleteAutoResponse(r.PathValue("guild_id"), r.PathValue("trigger")); err != nil {
+		jsonError(w, err.Error(), customStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -47,6 +47,7 @@ func (ui *UIService) queuesHandler(w http.ResponseWriter, r *http.Request) {
 			"guild_id":   guildID,
 			"channel_id": queue.VoiceChannelID(),
 			"tracks":     queue.Tracks(), // first track is currently playing
+			"history":    queue.History(),
 		})
 	}
 	jsonSuccess(w, response)
@@ -113,7 +114,11 @@ func (ui *UIService) handleQueuePlay(guildID string, payload QueueCommandPayload
 		return errors.New("VoiceChannelID is required for play command")
 	}
 
-	_, _, err := ui.bs.MS.PlayToVC(payload.Args, payload.VoiceChannelID, guildID)
+	if payload.Position < 0 {
+		return errors.New("position must be positive")
+	}
+
+	_, _, _, err := ui.bs.MS.PlayToVC(payload.Args, payload.VoiceChannelID, guildID, "", "", payload.Position)
 	return err
 }
 
@@ -134,6 +139,26 @@ func (ui *UIService) handleQueueSkip(guildID string, payload QueueCommandPayload
 	return queue.PlayNext(ui.bs.MS, true)
 }
 
+func (ui *UIService) handleQueuePrevious(guildID string, payload QueueCommandPayload) error {
+	queue := ui.bs.MS.GetQueue(guildID)
+	if queue == nil {
+		return errors.New("no active queue for this guild")
+	}
+	_, ok, err := queue.Previous(ui.bs.MS)
+	if !ok {
+		return errors.New("no previous track")
+	}
+	return err
+}
+
+func (ui *UIService) handleQueueReplay(guildID string, payload QueueCommandPayload) error {
+	queue := ui.bs.MS.GetQueue(guildID)
+	if queue == nil {
+		return errors.New("no active queue for this guild")
+	}
+	return queue.Replay(ui.bs.MS)
+}
+
 func (ui *UIService) handleQueueStop(guildID string, payload QueueCommandPayload) error {
 	ui.bs.MS.DeleteQueue(guildID)
 	return nil
@@ -0,0 +1,158 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/birabittoh/disgord/src/music"
+	"github.com/birabittoh/miri"
+)
+
+type PlaylistSummary struct {
+	Key     string    `json:"key"` // owner of the playlist, either "guild:<id>" or "user:<id>"
+	Name    string    `json:"name"`
+	Owner   string    `json:"owner"`
+	Tracks  int       `json:"tracks"`
+	Created time.Time `json:"created"`
+}
+
+type PlaylistTrack struct {
+	ID    string           `json:"id"`
+	Track *miri.SongResult `json:"track,omitempty"` // nil when the track is no longer available
+}
+
+type PlaylistTrackPayload struct {
+	Query string `json:"query"`
+}
+
+// playlistService returns the music service, replying with an error when it is disabled.
+func (ui *UIService) playlistService(w http.ResponseWriter) *music.MusicService {
+	if !ui.IsBotEnabled() || ui.bs.MS == nil {
+		jsonError(w, "Music service is disabled", http.StatusServiceUnavailable)
+		return nil
+	}
+	return ui.bs.MS
+}
+
+func playlistStatus(err error) int {
+	switch {
+	case errors.Is(err, music.ErrPlaylistNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, music.ErrPlaylistIndex), errors.Is(err, music.ErrNoResults):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (ui *UIService) playlistsHandler(w http.ResponseWriter, r *http.Request) {
+	if !ui.IsBotEnabled() || ui.bs.MS == nil {
+		jsonSuccess(w, []any{})
+		return
+	}
+
+	response := []PlaylistSummary{}
+	for key, lists := range ui.bs.MS.Playlists() {
+		for _, p := range lists {
+			response = append(response, PlaylistSummary{
+				Key:     key,
+				Name:    p.Name,
+				Owner:   p.Owner,
+				Tracks:  len(p.Tracks),
+				Created: p.Created,
+			})
+		}
+	}
+
+	slices.SortFunc(response, func(a, b PlaylistSummary) int {
+		if c := strings.Compare(a.Key, b.Key); c != 0 {
+			return c
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	jsonSuccess(w, response)
+}
+
+func (ui *UIService) playlistHandler(w http.ResponseWriter, r *http.Request) {
+	ms := ui.playlistService(w)
+	if ms == nil {
+		return
+	}
+
+	p, ok := ms.GetPlaylist(r.PathValue("key"), r.PathValue("name"))
+	if !ok {
+		jsonError(w, music.ErrPlaylistNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	tracks := make([]PlaylistTrack, 0, len(p.Tracks))
+	for i, track := range ms.ResolveTracks(p.Tracks) {
+		tracks = append(tracks, PlaylistTrack{ID: p.Tracks[i], Track: track})
+	}
+
+	jsonSuccess(w, map[string]any{
+		"key":    r.PathValue("key"),
+		"name":   p.Name,
+		"owner":  p.Owner,
+		"tracks": tracks,
+	})
+}
+
+func (ui *UIService) playlistDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	ms := ui.playlistService(w)
+	if ms == nil {
+		return
+	}
+
+	if err := ms.DeletePlaylist(r.PathValue("key"), r.PathValue("name")); err != nil {
+		jsonError(w, err.Error(), playlistStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ui *UIService) playlistAddTrackHandler(w http.ResponseWriter, r *http.Request) {
+	ms := ui.playlistService(w)
+	if ms == nil {
+		return
+	}
+
+	var payload PlaylistTrackPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || strings.TrimSpace(payload.Query) == "" {
+		jsonError(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	track, err := ms.AddToPlaylist(r.PathValue("key"), r.PathValue("name"), payload.Query)
+	if err != nil {
+		jsonError(w, err.Error(), playlistStatus(err))
+		return
+	}
+	jsonSuccess(w, track)
+}
+
+func (ui *UIService) playlistRemoveTrackHandler(w http.ResponseWriter, r *http.Request) {
+	ms := ui.playlistService(w)
+	if ms == nil {
+		return
+	}
+
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil {
+		jsonError(w, "Invalid track index", http.StatusBadRequest)
+		return
+	}
+
+	_, err = ms.UpdatePlaylist(r.PathValue("key"), r.PathValue("name"), func(p *music.Playlist) error {
+		return p.RemoveTrack(index)
+	})
+	if err != nil {
+		jsonError(w, err.Error(), playlistStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/birabittoh/disgord/src/globals"
+	"github.com/birabittoh/disgord/src/music"
+)
+
+const (
+	statsHistoryLimit = 50
+	statsTopLimit     = 10
+)
+
+func (ui *UIService) statsPageHandler(w http.ResponseWriter, r *http.Request) {
+	var b bytes.Buffer
+	err := ui.statsTemplate.Execute(&b, map[string]any{
+		"botName":  ui.botName,
+		"commitID": globals.CommitID,
+		"periods":  music.StatsPeriods,
+	})
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b.Bytes())
+}
+
+// statsSince reads the period query parameter, defaulting to a week.
+func statsSince(r *http.Request) (time.Time, bool) {
+	period := r.URL.Query().Get("period")
+	if period != "" && !slices.Contains(music.StatsPeriods, period) {
+		return time.Time{}, false
+	}
+	return music.PeriodStart(period, time.Now()), true
+}
+
+func (ui *UIService) statsHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	ms := ui.playlistService(w)
+	if ms == nil {
+		return
+	}
+
+	since, ok := statsSince(r)
+	if !ok {
+		jsonError(w, "Invalid period", http.StatusBadRequest)
+		return
+	}
+
+	jsonSuccess(w, ms.History(r.PathValue("guild_id"), since, statsHistoryLimit))
+}
+
+func (ui *UIService) statsTopHandler(w http.ResponseWriter, r *http.Request) {
+	ms := ui.playlistService(w)
+	if ms == nil {
+		return
+	}
+
+	since, ok := statsSince(r)
+	if !ok {
+		jsonError(w, "Invalid period", http.StatusBadRequest)
+		return
+	}
+
+	var top func(string, time.Time, int) []music.StatEntry
+	switch r.PathValue("kind") {
+	case "tracks":
+		top = ms.TopTracks
+	case "artists":
+		top = ms.TopArtists
+	case "listeners":
+		top = ms.TopListeners
+	default:
+		jsonError(w, "Invalid chart, use tracks, artists or listeners", http.StatusBadRequest)
+		return
+	}
+
+	jsonSuccess(w, top(r.PathValue("guild_id"), since, statsTopLimit))
+}
+
+// statsCommandsHandler returns how often each command ran since the bot started.
+func (ui *UIService) statsCommandsHandler(w http.ResponseWriter, r *http.Request) {
+	if !ui.IsBotEnabled() {
+		jsonSuccess(w, map[string]any{})
+		return
+	}
+
+	jsonSuccess(w, ui.bs.CommandMetrics())
+}
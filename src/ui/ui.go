@@ -21,6 +21,7 @@ type UIService struct {
 
 	mux            *http.ServeMux
 	indexTemplate  *template.Template
+	statsTemplate  *template.Template
 	validQueueCmds map[string]func(string, QueueCommandPayload) error
 	queueCmds      []string
 	botName        string
@@ -31,6 +32,7 @@ type QueueCommandPayload struct {
 	Command        string `json:"command"`
 	Args           string `json:"args,omitempty"`
 	VoiceChannelID string `json:"voice_channel_id,omitempty"`
+	Position       int    `json:"position,omitempty"` // play only, 0 appends
 }
 
 type EnabledPayload struct {
@@ -47,23 +49,40 @@ func NewUIService(bs *bot.BotService) *UIService {
 		})).With("service", globals.LoggerUI),
 		mux:           http.NewServeMux(),
 		indexTemplate: template.Must(template.ParseFiles("templates" + globals.Sep + "index.html")),
+		statsTemplate: template.Must(template.ParseFiles("templates" + globals.Sep + "stats.html")),
 		botName:       bs.US.Session.State.User.Username,
 		inviteLink:    bs.US.GetInviteLink(),
 	}
 
 	ui.validQueueCmds = map[string]func(string, QueueCommandPayload) error{
-		"play":  ui.handleQueuePlay, // requires VoiceChannelID
-		"clear": ui.handleQueueClear,
-		"skip":  ui.handleQueueSkip,
-		"stop":  ui.handleQueueStop,
+		"play":     ui.handleQueuePlay, // requires VoiceChannelID
+		"clear":    ui.handleQueueClear,
+		"skip":     ui.handleQueueSkip,
+		"previous": ui.handleQueuePrevious,
+		"replay":   ui.handleQueueReplay,
+		"stop":     ui.handleQueueStop,
 	}
 
 	ui.mux.HandleFunc("GET /", ui.indexHandler)
+	ui.mux.HandleFunc("GET /stats", ui.statsPageHandler)
 	ui.mux.HandleFunc("GET /api/guilds", ui.guildsHandler)
 	ui.mux.HandleFunc("GET /api/queues", ui.queuesHandler)
 	ui.mux.HandleFunc("POST /api/guilds/{id}/leave", ui.guildLeaveHandler)
 	ui.mux.HandleFunc("GET /api/queues/commands", ui.queuesCommandsHandler)
 	ui.mux.HandleFunc("POST /api/queues/{guild_id}", ui.queuesCommandHandler)
+	ui.mux.HandleFunc("GET /api/playlists", ui.playlistsHandler)
+	ui.mux.HandleFunc("GET /api/playlists/{key}/{name}", ui.playlistHandler)
+	ui.mux.HandleFunc("DELETE /api/playlists/{key}/{name}", ui.playlistDeleteHandler)
+	ui.mux.HandleFunc("POST /api/playlists/{key}/{name}/tracks", ui.playlistAddTrackHandler)
+	ui.mux.HandleFunc("DELETE /api/playlists/{key}/{name}/tracks/{index}", ui.playlistRemoveTrackHandler)
+	ui.mux.HandleFunc("GET /api/custom/{guild_id}", ui.customHandler)
+	ui.mux.HandleFunc("PUT /api/custom/{guild_id}/commands/{name}", ui.customCommandSetHandler)
+	ui.mux.HandleFunc("DELETE /api/custom/{guild_id}/commands/{name}", ui.customCommandDeleteHandler)
+	ui.mux.HandleFunc("PUT /api/custom/{guild_id}/triggers/{trigger}", ui.autoResponseSetHandler)
+	ui.mux.HandleFunc("DELETE /api/custom/{guild_id}/triggers/{trigger}", ui.autoResponseDeleteHandler)
+	ui.mux.HandleFunc("GET /api/stats/commands", ui.statsCommandsHandler)
+	ui.mux.HandleFunc("GET /api/stats/{guild_id}/history", ui.statsHistoryHandler)
+	ui.mux.HandleFunc("GET /api/stats/{guild_id}/top/{kind}", ui.statsTopHandler)
 	ui.mux.HandleFunc("GET /api/bot/state", ui.getBotStateHandler)
 	ui.mux.HandleFunc("POST /api/bot/state", ui.postBotStateHandler)
 	ui.mux.HandleFunc("GET /healthz", ui.healthzHandler)